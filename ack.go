@@ -0,0 +1,162 @@
+package orvibo
+
+// Acknowledgements. Normally SetState, EmitIR and EmitRF return as soon as the packet is written, whether or
+// not the device heard it. Pass Acknowledged() and we'll wait for the device to answer, sending the command
+// again (waiting twice as long each time) if it doesn't, and return ErrNoAck if it never does
+
+import (
+	"context" // For pumping messages while we wait
+	"errors"  // For crafting our own errors
+	"time"    // For our timeouts
+)
+
+// AckTimeout is how long we wait for a device to acknowledge a command the first time. Each retry waits twice as long
+var AckTimeout = time.Second
+
+// AckRetries is how many times we send a command again if it isn't acknowledged
+var AckRetries = 3
+
+// ErrNoAck is returned when a device doesn't acknowledge a command, even after AckRetries retries
+var ErrNoAck = errors.New("The device didn't acknowledge the command")
+
+// Acknowledged makes a command wait for the device to acknowledge it, retrying if it doesn't. A command that's
+// never acknowledged counts as a failure for the device's circuit breaker (see BreakerThreshold)
+func Acknowledged() CommandOption {
+	return func(o *commandOptions) {
+		o.ack = true
+	}
+}
+
+// pendingAck is a command we're waiting for a device to acknowledge
+type pendingAck struct {
+	macAddress string                    // Which device we're waiting on
+	commandID  string                    // The command ID of the answer we're expecting
+	matches    func(message string) bool // Checks it's actually the answer to our command. nil means any message with commandID will do
+	done       chan bool                 // Closed when the answer comes in
+	answered   time.Time                 // When the answer came in. Set before done is closed
+}
+
+// sendCommand sends a control command via sendControl. If it's been asked to, it then waits for the device to answer
+// with commandID (and for matches to be happy with the answer), sending the command again if it doesn't
+func (c *Client) sendCommand(action string, msg string, device *Device, o commandOptions, commandID string, matches func(message string) bool) (bool, error) {
+	if o.ack == false || o.dryRun { // Fire and forget, or there's nothing to acknowledge
+		return c.sendControl(action, msg, device, o)
+	}
+
+	return c.untilAcknowledged(o.ctx, device, commandID, matches, func() (bool, error) {
+		return c.sendControl(action, msg, device, o)
+	})
+}
+
+// untilAcknowledged calls send, then waits for the device to answer with commandID (and for matches to be happy with
+// the answer), calling send again (and waiting twice as long) each time it doesn't. Without a ctx, it gives up after
+// AckRetries retries with ErrNoAck. With one, it keeps trying until ctx is done, and returns ctx.Err()
+func (c *Client) untilAcknowledged(ctx context.Context, device *Device, commandID string, matches func(message string) bool, send func() (bool, error)) (bool, error) {
+	retries := AckRetries
+	if ctx == nil {
+		ctx = context.Background()
+	} else {
+		retries = -1 // ctx says when to stop
+	}
+
+	ack := &pendingAck{macAddress: device.MACAddress, commandID: commandID, matches: matches, done: make(chan bool)}
+	c.acksLock.Lock()
+	c.pendingAcks[ack] = true
+	c.acksLock.Unlock()
+
+	defer func() {
+		c.acksLock.Lock()
+		delete(c.pendingAcks, ack)
+		c.acksLock.Unlock()
+	}()
+
+	wait := AckTimeout
+	for attempt := 0; retries < 0 || attempt <= retries; attempt++ {
+		sent := time.Now()
+		if success, err := send(); success == false { // Couldn't even send it, so there's no point waiting
+			return success, err
+		}
+
+		if c.waitForAck(ctx, ack, wait) {
+			c.recordSuccess(device)
+			c.recordLatency(ack.answered.Sub(sent))
+			return true, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			if err == context.DeadlineExceeded { // Ran out of time waiting for the device, rather than being cancelled
+				c.recordFailure(device)
+				c.passMessage("acktimeout", c.snapshot(device))
+			}
+
+			return false, err
+		}
+
+		wait *= 2
+	}
+
+	c.recordFailure(device) // A device that's gone away doesn't make our writes fail, so this is how the breaker finds out
+	c.passMessage("acktimeout", c.snapshot(device))
+	return false, ErrNoAck
+}
+
+// recordLatency adds how long a device took to acknowledge a command to our totals, for Stats
+func (c *Client) recordLatency(latency time.Duration) {
+	c.acksLock.Lock()
+	defer c.acksLock.Unlock()
+
+	c.ackedCommands++
+	c.ackLatency += latency
+}
+
+// waitForAck waits up to wait (or until parent is done) for an acknowledgement, reading messages ourselves if the
+// listener isn't running
+func (c *Client) waitForAck(parent context.Context, ack *pendingAck, wait time.Duration) bool {
+	ctx, cancel := context.WithTimeout(parent, wait)
+	defer cancel()
+
+	for {
+		select {
+		case <-ack.done:
+			return true
+		case <-ctx.Done():
+			return false
+		default:
+		}
+
+		if c.listening() { // The listener's reading for us, so we can wait for the answer itself
+			select {
+			case <-ack.done:
+				return true
+			case <-ctx.Done():
+				return false
+			case <-c.closing: // No answer is coming
+				return false
+			}
+		}
+
+		if c.pumpMessages(ctx, waitPollInterval) != nil { // Closed while we were waiting. No answer is coming
+			return false
+		}
+	}
+}
+
+// resolveAcks checks if a message is the answer to any commands we're waiting on
+func (c *Client) resolveAcks(commandID string, macAdd string, message string) {
+	c.acksLock.Lock()
+	defer c.acksLock.Unlock()
+
+	for ack := range c.pendingAcks {
+		if ack.macAddress != macAdd || ack.commandID != commandID {
+			continue
+		}
+
+		if ack.matches != nil && ack.matches(message) == false {
+			continue
+		}
+
+		ack.answered = time.Now()
+		close(ack.done)
+		delete(c.pendingAcks, ack) // So we don't close it twice
+	}
+}
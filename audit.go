@@ -0,0 +1,84 @@
+package orvibo
+
+// The audit log keeps a record of every control command we send out (state changes, IR, RF and so on),
+// so if a socket turns off in the middle of the night, you can find out what sent the command
+
+import (
+	"sync" // For locking our log while we write to it
+	"time" // For timestamps and latency
+)
+
+// AuditEntry is a single control command we've sent
+type AuditEntry struct {
+	Time       time.Time     // When we sent the command
+	Action     string        // What sent the command (e.g. "SetState" or "EmitIR")
+	MACAddress string        // Which device the command was for
+	Payload    string        // The hex packet we sent
+	DryRun     bool          // Was this a dry run? If so, nothing was actually sent
+	Success    bool          // Did the packet go out?
+	Err        error         // If it didn't, why not
+	Latency    time.Duration // How long it took to send
+}
+
+// AuditLogSize is how many entries we keep in the audit log. Once we hit this, the oldest entries are thrown away
+var AuditLogSize = 500
+
+var auditLog []AuditEntry // Our audit log, oldest first
+var auditLock sync.Mutex  // Commands can be sent from more than one goroutine (e.g. setInterval), so we lock the log
+
+// AuditLog returns a copy of the audit log, oldest entry first
+func AuditLog() []AuditEntry {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	entries := make([]AuditEntry, len(auditLog))
+	copy(entries, auditLog)
+	return entries
+}
+
+// ClearAuditLog empties out the audit log
+func ClearAuditLog() {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	auditLog = nil
+}
+
+// sendControl sends a control command via SendMessage, then records it in the audit log.
+// If the command is a dry run, or the device is in a quiet window, we skip SendMessage but still log it
+func (c *Client) sendControl(action string, msg string, device *Device, opts commandOptions) (bool, error) {
+	var success = true
+	var err error
+
+	start := time.Now()
+	if opts.critical == false {
+		err = checkQuietWindows(device)
+	}
+
+	if err != nil { // We're in a quiet window, so let the calling code know why nothing happened
+		success = false
+		c.passMessage("quietwindow", c.snapshot(device))
+	} else if opts.dryRun == false {
+		success, err = c.SendMessage(msg, device)
+	}
+
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	auditLog = append(auditLog, AuditEntry{
+		Time:       start,
+		Action:     action,
+		MACAddress: device.MACAddress,
+		Payload:    msg,
+		DryRun:     opts.dryRun,
+		Success:    success,
+		Err:        err,
+		Latency:    time.Since(start),
+	})
+
+	if AuditLogSize > 0 && len(auditLog) > AuditLogSize { // Too many? Drop the oldest ones
+		auditLog = auditLog[len(auditLog)-AuditLogSize:]
+	}
+
+	return success, err
+}
@@ -0,0 +1,60 @@
+package orvibo
+
+// Automatic discovery, so new devices are picked up as they join the network, without the calling
+// code running its own timer
+
+import (
+	"errors" // For crafting our own errors
+	"time"   // For our interval
+)
+
+// StartAutoDiscovery calls Discover straight away, then again every interval, until StopAutoDiscovery
+// or Close is called. Calling it while it's already running just changes the interval
+func (c *Client) StartAutoDiscovery(interval time.Duration) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return errors.New("Auto discovery interval must be more than 0")
+	}
+
+	c.StopAutoDiscovery()
+
+	c.autoDiscoveryLock.Lock()
+	defer c.autoDiscoveryLock.Unlock()
+
+	stop := make(chan bool)
+	c.stopAutoDiscovery = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			c.Discover()
+
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			case <-c.closing:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopAutoDiscovery stops the automatic discovery started by StartAutoDiscovery. It's safe to call
+// even if it isn't running
+func (c *Client) StopAutoDiscovery() {
+	c.autoDiscoveryLock.Lock()
+	defer c.autoDiscoveryLock.Unlock()
+
+	if c.stopAutoDiscovery != nil {
+		close(c.stopAutoDiscovery)
+		c.stopAutoDiscovery = nil
+	}
+}
@@ -0,0 +1,121 @@
+package orvibo
+
+// A per-device circuit breaker. If a device keeps failing, we stop sending to it for a while and fail straight
+// away instead, so one dead socket doesn't hold everything else up. Hearing anything from the device closes
+// the breaker again. While it's tripped, we send the device a discovery message every BreakerCooldown to see if
+// it's back, so the breaker closes by itself without anyone having to send it a command.
+//
+// UDP doesn't tell us when nobody's listening, so a write to a socket that's been unplugged almost always works.
+// The failures that count are commands sent with Acknowledged() that are never acknowledged (plus the rare write
+// that does fail). Without Acknowledged(), a dead device won't trip its breaker
+
+import (
+	"fmt"  // For building our error messages
+	"time" // For our cooldown
+
+	"github.com/Grayda/go-orvibo/packet" // For our probe
+)
+
+// BreakerThreshold is how many failures in a row trip a device's breaker. 0 turns the breaker off
+var BreakerThreshold = 5
+
+// BreakerCooldown is how long a tripped breaker stays shut before we let one command through to see if the device is back
+var BreakerCooldown = 30 * time.Second
+
+// breakerAllows returns an error if the device's breaker is tripped. Once the cooldown has passed,
+// one command is let through as a probe. Hold devicesLock while calling it
+func breakerAllows(device *Device) error {
+	if device.Tripped == false || BreakerThreshold <= 0 {
+		return nil
+	}
+
+	if time.Since(device.trippedAt) > BreakerCooldown { // Give it another go, but only one until we know how it went
+		device.trippedAt = time.Now()
+		return nil
+	}
+
+	return fmt.Errorf("%s has failed %d times in a row, so we're not sending to it. Trying again after %s", device.MACAddress, device.failures, BreakerCooldown)
+}
+
+// recordFailure counts a failure against a device, tripping its breaker if it's failed too many times
+func (c *Client) recordFailure(device *Device) {
+	c.devicesLock.Lock()
+	device.failures++
+	if device.Tripped {
+		device.trippedAt = time.Now() // Our probe failed, so start the cooldown again
+		c.devicesLock.Unlock()
+		return
+	}
+
+	if BreakerThreshold <= 0 || device.failures < BreakerThreshold {
+		c.devicesLock.Unlock()
+		return
+	}
+
+	device.Tripped = true
+	device.trippedAt = time.Now()
+	failures := device.failures
+	probing := device.probing // Still checking on it from the last time it tripped?
+	device.probing = true
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	getLogger().Warn("%s has failed %d times in a row. Not sending to it for %s", device.MACAddress, failures, BreakerCooldown)
+	c.passMessage("breakertripped", snapshot)
+
+	if probing == false {
+		go c.probeBreaker(device, BreakerCooldown)
+	}
+}
+
+// probeBreaker sends a discovery message straight to a tripped device every cooldown, until it answers
+// (which closes the breaker, through recordSuccess) or we're closed. A device answers discovery whether we've
+// subscribed to it or not, so this works for sockets and AllOnes alike
+func (c *Client) probeBreaker(device *Device, cooldown time.Duration) {
+	msg, err := packet.NewPacket(packet.DiscoverMAC, device.MACAddress, "")
+	if err != nil { // Not a MAC address we can probe. Sending it a command after the cooldown is all we can do
+		c.devicesLock.Lock()
+		device.probing = false
+		c.devicesLock.Unlock()
+		return
+	}
+
+	for {
+		select {
+		case <-time.After(cooldown):
+		case <-c.closing:
+			return
+		}
+
+		c.devicesLock.Lock()
+		if device.Tripped == false || c.Devices[device.MACAddress] != device { // It's answered since we last checked, or it's been removed
+			device.probing = false
+			c.devicesLock.Unlock()
+			return
+		}
+
+		probe := &Device{IP: device.IP, ifIndex: device.ifIndex} // No MAC address, so the breaker doesn't stop it
+		c.devicesLock.Unlock()
+
+		if _, err := c.SendMessage(msg, probe); err != nil {
+			getLogger().Debug("Couldn't probe %s: %v", device.MACAddress, err)
+		}
+	}
+}
+
+// recordSuccess clears a device's failure count, closing its breaker if it was tripped
+func (c *Client) recordSuccess(device *Device) {
+	c.devicesLock.Lock()
+	device.failures = 0
+	if device.Tripped == false {
+		c.devicesLock.Unlock()
+		return
+	}
+
+	device.Tripped = false
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	getLogger().Info("%s is answering again", device.MACAddress)
+	c.passMessage("breakerclosed", snapshot)
+}
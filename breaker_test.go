@@ -0,0 +1,80 @@
+package orvibo_test
+
+import (
+	"testing"
+	"time" // For our cooldown
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/orvibotest"
+	"github.com/Grayda/go-orvibo/packet"
+)
+
+// withBreaker sets BreakerThreshold and BreakerCooldown for one test, putting them back afterwards
+func withBreaker(t *testing.T, threshold int, cooldown time.Duration) {
+	oldThreshold, oldCooldown := orvibo.BreakerThreshold, orvibo.BreakerCooldown
+	orvibo.BreakerThreshold, orvibo.BreakerCooldown = threshold, cooldown
+	t.Cleanup(func() { orvibo.BreakerThreshold, orvibo.BreakerCooldown = oldThreshold, oldCooldown })
+}
+
+func TestBreaker(t *testing.T) {
+	withBreaker(t, 2, 100*time.Millisecond)
+	withAckSettings(t, 10*time.Millisecond, 0)
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	c.Listen()
+
+	for i := 0; i < 2; i++ { // Nobody's answering, so both of these fail
+		if _, err := c.SetState(testSocket, true, orvibo.Acknowledged()); err != orvibo.ErrNoAck {
+			t.Fatalf("SetState returned %v, not ErrNoAck", err)
+		}
+	}
+
+	expectEvent(t, c, "breakertripped")
+	if device, _ := c.GetDevice(testSocket); device.Tripped == false {
+		t.Error("The socket isn't marked as tripped")
+	}
+
+	// Now we don't even try
+	if _, err := c.SetState(testSocket, true); err == nil {
+		t.Fatal("SetState worked with the breaker tripped")
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 2 {
+		t.Fatalf("Sent %d commands, not 2", len(sent))
+	}
+
+	// Once the cooldown's up, the device is probed without anyone sending it a command. It answers, which closes the breaker
+	answer(t, transport, packet.DiscoverMAC, func(sent orvibotest.SentPacket) []string {
+		return []string{discoveryReply(t, testSocket, "SOC002", "01")}
+	})
+
+	expectEvent(t, c, "breakerclosed")
+	if sent := sentWith(transport, packet.DiscoverMAC); len(sent) == 0 || sent[0].To.String() != testAddr.String() {
+		t.Errorf("Sent probes %v, not one to %s", sent, testAddr)
+	}
+
+	if _, err := c.SetState(testSocket, true); err != nil {
+		t.Errorf("SetState returned %v once the breaker closed", err)
+	}
+}
+
+func TestBreakerProbeFails(t *testing.T) {
+	withBreaker(t, 1, 30*time.Millisecond)
+	withAckSettings(t, 10*time.Millisecond, 0)
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	c.Listen()
+
+	c.SetState(testSocket, true, orvibo.Acknowledged())
+	expectEvent(t, c, "breakertripped")
+
+	// Nobody answers the probes, so they keep coming, one every cooldown
+	time.Sleep(200 * time.Millisecond)
+	if sent := sentWith(transport, packet.DiscoverMAC); len(sent) < 2 {
+		t.Errorf("Sent %d probes, not one every cooldown", len(sent))
+	}
+
+	if device, _ := c.GetDevice(testSocket); device.Tripped == false {
+		t.Error("The breaker closed without the socket answering")
+	}
+}
@@ -0,0 +1,91 @@
+package orvibo
+
+// Subnet-directed broadcasts. A lot of routers (and hosts with more than one interface) drop packets sent to
+// 255.255.255.255, or only send them out of one interface. So instead, we work out the broadcast address of
+// each of our subnets (e.g. 192.168.1.255 for 192.168.1.0/24) and send to each of those
+
+import (
+	"net" // For looking at our interfaces
+)
+
+// BroadcastInterface, if set, is the name of the only interface we broadcast on (e.g. eth1).
+// If it's blank, we broadcast on every interface that can
+var BroadcastInterface = ""
+
+// broadcastTarget is somewhere a broadcast needs to go, and the interface to send it out of
+type broadcastTarget struct {
+	addr    *net.UDPAddr
+	ifIndex int
+}
+
+// broadcastTargets works out the directed broadcast address of every IPv4 subnet we're on (or just those on
+// BroadcastInterface or Interface, if they're set). If we can't find any, we fall back to 255.255.255.255 and
+// let the OS decide
+func (c *Client) broadcastTargets() []broadcastTarget {
+	var targets []broadcastTarget
+
+	only := c.BroadcastInterface
+	if only == "" {
+		only = c.Interface
+	}
+
+	ifaces, err := net.Interfaces()
+	if err == nil {
+		for _, iface := range ifaces {
+			if only != "" && iface.Name != only {
+				continue
+			}
+
+			if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 || iface.Flags&net.FlagLoopback != 0 {
+				continue
+			}
+
+			addrs, err := iface.Addrs()
+			if err != nil {
+				continue
+			}
+
+			for _, a := range addrs {
+				ipnet, ok := a.(*net.IPNet)
+				if ok == false {
+					continue
+				}
+
+				if bcast := directedBroadcast(ipnet); bcast != nil {
+					targets = append(targets, broadcastTarget{addr: &net.UDPAddr{IP: bcast, Port: devicePort}, ifIndex: iface.Index})
+				}
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		targets = append(targets, broadcastTarget{addr: &net.UDPAddr{IP: net.IPv4bcast, Port: devicePort}})
+	}
+
+	return targets
+}
+
+// directedBroadcast returns the broadcast address of an IPv4 subnet (the address with all the host bits set),
+// or nil if it isn't IPv4
+func directedBroadcast(ipnet *net.IPNet) net.IP {
+	ip := ipnet.IP.To4()
+	if ip == nil {
+		return nil
+	}
+
+	mask := ipnet.Mask
+	if len(mask) == net.IPv6len { // An IPv4 mask in IPv6 form. The last 4 bytes are the ones we want
+		mask = mask[12:]
+	}
+
+	if len(mask) != net.IPv4len {
+		return nil
+	}
+
+	bcast := make(net.IP, net.IPv4len)
+	for i := range ip {
+		bcast[i] = ip[i] | ^mask[i]
+	}
+
+	return bcast
+}
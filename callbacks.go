@@ -0,0 +1,98 @@
+package orvibo
+
+// Event callbacks. Instead of a big select / switch over Events, you can register a function for just the events
+// you care about with On (or Once, if you only want the next one). Callbacks are called on the goroutine that
+// raised the event, which is the listener goroutine for anything a device sent us, so keep them quick. If a
+// callback needs to wait for a device (e.g. SetState with Acknowledged()), start a goroutine for it.
+// Events still go out on Events as well, so the two can be mixed
+
+import (
+	"sync" // Callbacks can be registered from any goroutine
+)
+
+// EventHandler is a function that's called when an event is raised
+type EventHandler func(event EventStruct)
+
+// HandlerPanicEvent is the Payload of a "handlerpanic" event
+type HandlerPanicEvent struct {
+	Event     EventStruct // The event the callback was handling
+	Recovered interface{} // What it panicked with
+}
+
+// callback is one registered EventHandler
+type callback struct {
+	handler EventHandler
+	once    bool // Remove it after it's been called once
+}
+
+// callbackList is every callback registered on a Client, keyed by event name
+type callbackList struct {
+	byName map[string][]*callback
+	lock   sync.Mutex
+}
+
+// On calls handler every time the named event (e.g. "statechanged") is raised. Use EventType.String() if
+// you'd rather use the typed names (e.g. EventStateChanged.String())
+func (c *Client) On(name string, handler EventHandler) {
+	c.addCallback(name, &callback{handler: handler})
+}
+
+// Once calls handler the next time the named event is raised, and then forgets about it
+func (c *Client) Once(name string, handler EventHandler) {
+	c.addCallback(name, &callback{handler: handler, once: true})
+}
+
+// Off removes every callback registered for the named event
+func (c *Client) Off(name string) {
+	c.callbacks.lock.Lock()
+	defer c.callbacks.lock.Unlock()
+
+	delete(c.callbacks.byName, name)
+}
+
+// addCallback registers a callback against an event name
+func (c *Client) addCallback(name string, cb *callback) {
+	c.callbacks.lock.Lock()
+	defer c.callbacks.lock.Unlock()
+
+	if c.callbacks.byName == nil {
+		c.callbacks.byName = make(map[string][]*callback)
+	}
+
+	c.callbacks.byName[name] = append(c.callbacks.byName[name], cb)
+}
+
+// dispatch calls every callback registered for an event. Once callbacks are removed before they're called,
+// so they can't run twice if the same event is raised on another goroutine at the same time
+func (c *Client) dispatch(event EventStruct) {
+	c.callbacks.lock.Lock()
+	registered := c.callbacks.byName[event.Name]
+	var keep []*callback
+	for _, cb := range registered {
+		if cb.once == false {
+			keep = append(keep, cb)
+		}
+	}
+
+	if len(keep) != len(registered) {
+		c.callbacks.byName[event.Name] = keep
+	}
+	c.callbacks.lock.Unlock() // Unlocked before we call anything, so callbacks can call On and Off
+
+	for _, cb := range registered {
+		c.runCallback(cb.handler, event)
+	}
+}
+
+// runCallback calls a callback, and turns a panic into a "handlerpanic" event so one bad callback can't
+// take down the listener. "handlerpanic" only goes out on Events, never to callbacks, so it can't loop
+func (c *Client) runCallback(handler EventHandler, event EventStruct) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			getLogger().Error("Callback for %q panicked: %v", event.Name, recovered)
+			c.queueEvent(EventStruct{Name: "handlerpanic", Type: EventHandlerPanic, DeviceInfo: event.DeviceInfo, Payload: HandlerPanicEvent{Event: event, Recovered: recovered}, Count: 1})
+		}
+	}()
+
+	handler(event)
+}
@@ -0,0 +1,204 @@
+package orvibo
+
+// A Client is one controller: its own UDP connection, its own list of devices and its own events channel. Most
+// programs only need one, and can just use the package-level functions (Prepare, Discover etc.), which use a
+// default Client. If you need more than one (e.g. on different ports, or in tests), use NewClient.
+//
+// Quiet windows, event coalescing, the circuit breaker, the audit log, tracing and virtual sockets are shared
+// by every Client, and are still set with their package-level variables
+
+import (
+	"sync" // For locking our discovery streams and coalesced events
+	"time" // For DeviceTTL
+)
+
+// devicePort is the port Orvibo devices listen on. We always send to this, whatever port we're listening on
+const devicePort = 10000
+
+// Client is an Orvibo controller. Create one with NewClient, then call Prepare on it
+type Client struct {
+	Devices map[string]*Device // All the Devices this client has discovered. Use GetDevice or GetDevices while we're listening
+	Events  chan EventStruct   // Events for this client. Read from it, or events will be dropped
+
+	// Settings. These work the same way as the package-level variables of the same name
+	AutoSubscribe      bool               // Subscribe to each new device as soon as it's discovered
+	AutoQuery          bool               // Query each device as soon as its subscription is confirmed
+	AutoResubscribe    bool               // Renew each subscription before it lapses
+	RawStateEvents     bool               // Raise "statechanged" for every state confirmation, even repeats
+	Passive            bool               // Only listen. Never send anything
+	ReceiveBufferSize  int                // The largest message (in bytes) we can read
+	DeviceTTL          time.Duration      // How long we can go without hearing from a device before it's purged. 0 means never
+	ArchivePurged      bool               // Move purged devices into ArchivedDevices instead of forgetting them
+	ArchivedDevices    map[string]*Device // Devices that have been purged, if ArchivePurged is true
+	AllowedOUIs        []string           // Only deal with devices whose MAC addresses start with one of these. Empty means any device
+	BroadcastInterface string             // The only interface we broadcast on. Blank means every interface (or Interface, if that's set)
+	Interface          string             // The interface we listen and broadcast on (e.g. eth1). Blank means any. Set before Prepare
+	BindAddr           string             // The address we listen on (e.g. 192.168.1.10:10000). Overrides Interface and Port. Set before Prepare
+	Port               int                // The port we listen on. 0 means 10000, the port devices use. Set before Prepare
+	ReusePort          bool               // Share our port with other programs that do the same (SO_REUSEADDR / SO_REUSEPORT). Set before Prepare
+
+	conn        Transport    // UDP Connection. A *net.UDPConn, unless WithTransport gave us something else
+	transport   Transport    // Set by WithTransport, for Prepare to use instead of opening a UDP connection
+	state       int32        // Where we're at (unprepared, ready or closed). Use atomic to read and write it
+	deviceCount int          // How many items we've discovered
+	devicesLock sync.RWMutex // Covers Devices, ArchivedDevices, deviceCount and every Device's fields. See devices.go
+	readBuffer  []byte       // Where CheckForMessages reads into. Sized from ReceiveBufferSize
+	ownIP       string       // Our own IP address, so we can ignore our own broadcasts
+
+	discoverStreams     map[chan *Device]bool // All the discovery streams that are currently open
+	discoverStreamsLock sync.Mutex            // Streams are opened and closed from other goroutines
+
+	deviceStreams     map[string]map[chan EventStruct]bool // Open device event streams, keyed by MAC address
+	deviceStreamsLock sync.Mutex                           // Also opened and closed from other goroutines
+
+	coalescing     map[coalesceKey]*pendingEvent // Events currently inside their coalescing window
+	coalescingLock sync.Mutex                    // The window closes on another goroutine, so we lock
+
+	stopListening chan bool  // Closed to tell the background listener to stop
+	listenerDone  chan bool  // Closed by the background listener when it's finished. nil if it isn't running
+	listenLock    sync.Mutex // Listen and Stop can be called from different goroutines
+
+	stopAutoDiscovery chan bool  // Closed to stop automatic discovery. nil if it isn't running
+	autoDiscoveryLock sync.Mutex // StartAutoDiscovery and StopAutoDiscovery can be called from different goroutines
+
+	stopHealthMonitor chan bool  // Closed to stop the health monitor. nil if it isn't running
+	healthMonitorLock sync.Mutex // StartHealthMonitor and StopHealthMonitor can be called from different goroutines
+
+	pendingAcks   map[*pendingAck]bool // Commands we're waiting for devices to acknowledge
+	ackedCommands int                  // How many commands have been acknowledged, for Stats
+	ackLatency    time.Duration        // How long, all up, devices took to acknowledge them
+	acksLock      sync.Mutex           // Acknowledgements can come in on the listener goroutine
+
+	callbacks callbackList // Callbacks registered with On and Once
+
+	rawTaps     []RawPacketHandler // Handlers registered with OnRawPacket
+	rawTapsLock sync.Mutex         // Packets are sent and received on different goroutines
+
+	groups     map[string][]string // Device groups made with CreateGroup. Lists of MAC addresses, keyed by group name
+	groupsLock sync.Mutex          // Groups can be changed while group commands are going out
+
+	droppedEvents   uint64 // How many events we've dropped because Events was full. Use atomic to read and write it
+	unreportedDrops uint64 // How many of those we haven't raised "eventsdropped" for yet
+
+	closing      chan bool    // Closed by Close, to wake up anything waiting on a device
+	eventsClosed bool         // True once Close has closed Events
+	eventsLock   sync.RWMutex // Events are raised from all sorts of goroutines, and Close closes the channel
+}
+
+// ClientOption changes a setting on a new Client. Pass as many as you like to NewClient
+type ClientOption func(*Client)
+
+// NewClient creates a new Client, with its own (empty) list of devices and its own events channel.
+// Call Prepare on it before anything else
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		Devices:           make(map[string]*Device),
+		Events:            make(chan EventStruct, EventBufferSize),
+		ReceiveBufferSize: 8192,
+		ArchivedDevices:   make(map[string]*Device),
+		discoverStreams:   make(map[chan *Device]bool),
+		deviceStreams:     make(map[string]map[chan EventStruct]bool),
+		coalescing:        make(map[coalesceKey]*pendingEvent),
+		pendingAcks:       make(map[*pendingAck]bool),
+		groups:            make(map[string][]string),
+		closing:           make(chan bool),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithAutoSubscribe sets AutoSubscribe on a new Client
+func WithAutoSubscribe(autoSubscribe bool) ClientOption {
+	return func(c *Client) {
+		c.AutoSubscribe = autoSubscribe
+	}
+}
+
+// WithAutoQuery sets AutoQuery on a new Client
+func WithAutoQuery(autoQuery bool) ClientOption {
+	return func(c *Client) {
+		c.AutoQuery = autoQuery
+	}
+}
+
+// WithAutoResubscribe sets AutoResubscribe on a new Client
+func WithAutoResubscribe(autoResubscribe bool) ClientOption {
+	return func(c *Client) {
+		c.AutoResubscribe = autoResubscribe
+	}
+}
+
+// WithPassive sets Passive on a new Client
+func WithPassive(passive bool) ClientOption {
+	return func(c *Client) {
+		c.Passive = passive
+	}
+}
+
+// WithAllowedOUIs sets AllowedOUIs on a new Client
+func WithAllowedOUIs(ouis ...string) ClientOption {
+	return func(c *Client) {
+		c.AllowedOUIs = ouis
+	}
+}
+
+// WithBroadcastInterface sets BroadcastInterface on a new Client
+func WithBroadcastInterface(name string) ClientOption {
+	return func(c *Client) {
+		c.BroadcastInterface = name
+	}
+}
+
+// WithInterface sets Interface on a new Client (or one that's about to be prepared)
+func WithInterface(name string) ClientOption {
+	return func(c *Client) {
+		c.Interface = name
+	}
+}
+
+// WithBindAddr sets BindAddr on a new Client (or one that's about to be prepared)
+func WithBindAddr(addr string) ClientOption {
+	return func(c *Client) {
+		c.BindAddr = addr
+	}
+}
+
+// WithPort sets Port on a new Client (or one that's about to be prepared)
+func WithPort(port int) ClientOption {
+	return func(c *Client) {
+		c.Port = port
+	}
+}
+
+// WithReusePort sets ReusePort on a new Client (or one that's about to be prepared)
+func WithReusePort(reuse bool) ClientOption {
+	return func(c *Client) {
+		c.ReusePort = reuse
+	}
+}
+
+// WithTransport has Prepare use transport for sending and receiving, instead of opening a UDP connection.
+// Interface, BindAddr, Port and ReusePort are ignored. It's meant for tests (see orvibotest.Transport)
+func WithTransport(transport Transport) ClientOption {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithReceiveBufferSize sets ReceiveBufferSize on a new Client
+func WithReceiveBufferSize(size int) ClientOption {
+	return func(c *Client) {
+		c.ReceiveBufferSize = size
+	}
+}
+
+// WithEventBufferSize sets how many events can be waiting on the new Client's Events before they're dropped
+func WithEventBufferSize(size int) ClientOption {
+	return func(c *Client) {
+		c.Events = make(chan EventStruct, size)
+	}
+}
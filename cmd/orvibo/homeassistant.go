@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag" // For our options
+	"os"   // For writing our config
+	"time" // For how long to discover for
+
+	"github.com/Grayda/go-orvibo" // For controlling Orvibo stuff
+)
+
+// homeAssistant discovers devices for a while, then writes them out as Home Assistant config, ready to paste
+// into configuration.yaml
+func homeAssistant(args []string) error {
+	flags := flag.NewFlagSet("homeassistant", flag.ExitOnError)
+	output := flags.String("o", "", "Write the config to this file instead of stdout")
+	wait := flags.Duration("wait", 5*time.Second, "How long to wait for devices to answer")
+	flags.Parse(args)
+
+	orvibo.AutoSubscribe = true // Subscribing and querying gets us each socket's name
+	orvibo.AutoQuery = true
+
+	if _, err := orvibo.Prepare(); err != nil {
+		return err
+	}
+	defer orvibo.Close()
+
+	go func() { // We don't need the events, but someone has to read them
+		for range orvibo.Events {
+		}
+	}()
+
+	if err := orvibo.Listen(); err != nil {
+		return err
+	}
+
+	orvibo.Discover()
+	time.Sleep(*wait)
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		w = f
+	}
+
+	return orvibo.ExportHomeAssistant(w)
+}
@@ -0,0 +1,43 @@
+// Command orvibo is a command line tool for working with Orvibo devices. Run it with no arguments to see what it can do
+package main
+
+import (
+	"fmt" // For outputting messages
+	"os"  // For arguments and exit codes
+)
+
+// commands maps each subcommand to the function that runs it. Each one gets the arguments after its name
+var commands = map[string]func(args []string) error{
+	"trace":         trace,
+	"replay":        replay,
+	"homeassistant": homeAssistant,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, found := commands[os.Args[1]]
+	if found == false {
+		fmt.Fprintln(os.Stderr, "Unknown command", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// usage lists our subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: orvibo <command> [options]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  trace          Write every packet sent and received as JSON lines")
+	fmt.Fprintln(os.Stderr, "  replay         Feed a pcap capture through the library and write every event it raises as JSON lines")
+	fmt.Fprintln(os.Stderr, "  homeassistant  Discover devices and write them out as Home Assistant config")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json" // For writing events out
+	"errors"        // For crafting our own errors
+	"flag"          // For our options
+	"fmt"           // For our summary
+	"os"            // For writing events out
+
+	"github.com/Grayda/go-orvibo" // For controlling Orvibo stuff
+)
+
+// replay feeds a pcap capture through the library and writes every event it raises to stdout as JSON lines.
+// Nothing is sent to any device, as we never prepare a connection
+func replay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: orvibo replay <capture.pcap>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("Which capture should we replay?")
+	}
+
+	client := orvibo.NewClient(orvibo.WithPassive(true))
+	output := json.NewEncoder(os.Stdout)
+
+	type result struct {
+		replayed int
+		err      error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		replayed, err := client.ReplayPCAP(flags.Arg(0))
+		done <- result{replayed, err}
+	}()
+
+	for {
+		select {
+		case event := <-client.Events:
+			output.Encode(event)
+		case r := <-done:
+			for { // Write out whatever's left, then we're done
+				select {
+				case event := <-client.Events:
+					output.Encode(event)
+				default:
+					fmt.Fprintf(os.Stderr, "Replayed %d packets\n", r.replayed)
+					return r.err
+				}
+			}
+		}
+	}
+}
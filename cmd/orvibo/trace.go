@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"      // For our options
+	"os"        // For writing our trace
+	"os/signal" // For stopping on Ctrl+C
+	"time"      // For how long to trace for
+
+	"github.com/Grayda/go-orvibo" // For controlling Orvibo stuff
+)
+
+// trace writes every packet we see to stdout (or a file) as JSON lines, until Ctrl+C or -duration runs out
+func trace(args []string) error {
+	flags := flag.NewFlagSet("trace", flag.ExitOnError)
+	output := flags.String("o", "", "Write the trace to this file instead of stdout")
+	passive := flags.Bool("passive", false, "Only listen. Don't discover, subscribe or query anything")
+	duration := flags.Duration("duration", 0, "Stop after this long (e.g. 30s). Runs until Ctrl+C if not set")
+	flags.Parse(args)
+
+	orvibo.Trace = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		orvibo.Trace = f
+	}
+
+	orvibo.Passive = *passive
+	orvibo.AutoSubscribe = *passive == false // Subscribing and querying gets us more packets to look at
+	orvibo.AutoQuery = *passive == false
+
+	if _, err := orvibo.Prepare(); err != nil {
+		return err
+	}
+
+	// Close the connection when we're done, which makes CheckForMessages return ErrClosed and stops our loop
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	go func() {
+		if *duration > 0 {
+			select {
+			case <-stop:
+			case <-time.After(*duration):
+			}
+		} else {
+			<-stop
+		}
+
+		orvibo.Close()
+	}()
+
+	go func() { // We don't need the events, but someone has to read them
+		for range orvibo.Events {
+		}
+	}()
+
+	orvibo.Discover()
+	for {
+		if _, err := orvibo.CheckForMessages(); err == orvibo.ErrClosed {
+			return nil
+		}
+	}
+}
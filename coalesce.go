@@ -0,0 +1,89 @@
+package orvibo
+
+// Event coalescing. During a discovery storm we can raise the same event for the same device many times
+// in a row (e.g. "existingsocketfound" for every reply). If CoalesceEvents is on, the first one goes out straight
+// away, and any repeats inside CoalesceWindow are rolled up into a single event with a Count.
+//
+// Only events that say "nothing's changed" are rolled up (see coalescedEvents). Anything that reports a change,
+// like statechanged, or that isn't about a device, like parseerror, always goes out as it happens, since
+// rolling two of them up would lose one
+
+import (
+	"time" // For our coalescing window
+)
+
+// CoalesceEvents turns event coalescing on. It's off by default, so every event is passed on as it happens
+var CoalesceEvents = false
+
+// CoalesceWindow is how long we wait for repeats of an event before passing on the rolled up version
+var CoalesceWindow = 2 * time.Second
+
+// coalescedEvents are the events we roll up. They're all repeats of something we've already said
+var coalescedEvents = map[EventType]bool{
+	EventExistingSocketFound: true,
+	EventExistingAllOneFound: true,
+	EventSubscribed:          true,
+	EventQueried:             true,
+	EventSubscriptionRenewed: true,
+}
+
+// coalesceKey identifies "the same event": same name, same device, and the device in the same state
+type coalesceKey struct {
+	name       string
+	macAddress string
+	state      bool
+}
+
+// pendingEvent is an event we've seen repeats of, but haven't passed on yet
+type pendingEvent struct {
+	device  *Device     // The device, as of the latest repeat
+	payload interface{} // The payload of the latest repeat
+	count   int         // How many repeats we've seen, not counting the first one (which has already gone out)
+	timer   *time.Timer // Closes the window
+}
+
+// coalesce returns true if the event should be passed on now. If it's a repeat inside the window, it's counted
+// and false is returned. When the window closes, the repeats are passed on as one event, with a Count of how
+// many repeats it stands for
+func (c *Client) coalesce(message string, device *Device, payload interface{}) bool {
+	if coalescedEvents[eventType(message)] == false || device.MACAddress == "" { // Not a repeat we roll up
+		return true
+	}
+
+	key := coalesceKey{message, device.MACAddress, device.State}
+
+	c.coalescingLock.Lock()
+	defer c.coalescingLock.Unlock()
+
+	if pending, found := c.coalescing[key]; found { // A repeat. Count it and hold on to it
+		pending.device = device
+		pending.payload = payload
+		pending.count++
+		return false
+	}
+
+	c.coalescing[key] = &pendingEvent{device: device, payload: payload}
+	c.coalescing[key].timer = time.AfterFunc(CoalesceWindow, func() {
+		c.coalescingLock.Lock()
+		pending, found := c.coalescing[key]
+		delete(c.coalescing, key)
+		c.coalescingLock.Unlock()
+
+		if found && pending.count > 0 { // There were repeats, so pass them on as one
+			c.sendEvent(EventStruct{Name: message, Type: eventType(message), DeviceInfo: pending.device, Payload: pending.payload, Count: pending.count})
+		}
+	})
+
+	return true
+}
+
+// stopCoalescing closes every coalescing window without passing anything on. Used when we're closing down
+func (c *Client) stopCoalescing() {
+	c.coalescingLock.Lock()
+	defer c.coalescingLock.Unlock()
+
+	for key, pending := range c.coalescing {
+		pending.timer.Stop()
+		delete(c.coalescing, key)
+	}
+}
@@ -0,0 +1,92 @@
+package orvibo
+
+import (
+	"testing"
+	"time" // For the coalescing window
+)
+
+// withCoalescing turns CoalesceEvents on with a short window for one test, putting them back afterwards
+func withCoalescing(t *testing.T, window time.Duration) {
+	oldEvents, oldWindow := CoalesceEvents, CoalesceWindow
+	CoalesceEvents, CoalesceWindow = true, window
+	t.Cleanup(func() { CoalesceEvents, CoalesceWindow = oldEvents, oldWindow })
+}
+
+// drainEvents returns every event waiting on c.Events
+func drainEvents(c *Client) []EventStruct {
+	var events []EventStruct
+	for len(c.Events) > 0 {
+		events = append(events, <-c.Events)
+	}
+
+	return events
+}
+
+func TestCoalesceRepeats(t *testing.T) {
+	withCoalescing(t, 50*time.Millisecond)
+	c := NewClient()
+
+	for i := 0; i < 4; i++ {
+		c.passMessage("existingsocketfound", &Device{MACAddress: "accf00000001"})
+	}
+
+	events := drainEvents(c)
+	if len(events) != 1 || events[0].Count != 1 { // The first one goes straight out
+		t.Fatalf("Got %+v straight away, not one event", events)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	events = drainEvents(c)
+	if len(events) != 1 || events[0].Name != "existingsocketfound" || events[0].Count != 3 { // Then the three repeats, as one
+		t.Fatalf("Got %+v when the window closed, not one event with a Count of 3", events)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if events := drainEvents(c); len(events) != 0 {
+		t.Errorf("Got %+v after the window closed", events)
+	}
+}
+
+func TestCoalesceNoRepeats(t *testing.T) {
+	withCoalescing(t, 50*time.Millisecond)
+	c := NewClient()
+
+	c.passMessage("existingsocketfound", &Device{MACAddress: "accf00000001"})
+	time.Sleep(100 * time.Millisecond)
+
+	if events := drainEvents(c); len(events) != 1 { // Nothing to roll up, so nothing more goes out
+		t.Errorf("Got %+v, not one event", events)
+	}
+}
+
+func TestCoalesceKeepsChanges(t *testing.T) {
+	withCoalescing(t, time.Second)
+	c := NewClient()
+
+	// Changes are never rolled up, however close together they are
+	c.passEvent("statechanged", &Device{MACAddress: "accf00000001", State: true}, StateChangedEvent{OldState: false, NewState: true})
+	c.passEvent("statechanged", &Device{MACAddress: "accf00000001", State: false}, StateChangedEvent{OldState: true, NewState: false})
+
+	// Nor is anything that isn't about a device
+	c.passMessage("parseerror", &Device{})
+	c.passMessage("parseerror", &Device{})
+
+	// And confirmations of different states are different events
+	c.passMessage("subscribed", &Device{MACAddress: "accf00000001", State: true})
+	c.passMessage("subscribed", &Device{MACAddress: "accf00000001", State: false})
+
+	// As are the same event from different devices
+	c.passMessage("existingsocketfound", &Device{MACAddress: "accf00000001"})
+	c.passMessage("existingsocketfound", &Device{MACAddress: "accf00000002"})
+
+	events := drainEvents(c)
+	if len(events) != 8 {
+		t.Fatalf("Got %d events, not 8: %+v", len(events), events)
+	}
+
+	if events[0].Payload.(StateChangedEvent).NewState != true || events[1].Payload.(StateChangedEvent).NewState != false {
+		t.Errorf("Got state changes %+v and %+v, not on then off", events[0].Payload, events[1].Payload)
+	}
+
+	c.stopCoalescing()
+}
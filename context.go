@@ -0,0 +1,123 @@
+package orvibo
+
+// Context-aware versions of our network operations, so a device that never answers (or a read that never
+// returns) can be abandoned with a timeout or a cancel, rather than by killing the whole program. Like
+// WaitForDevice, the ones that wait for an answer read messages themselves (unless Listen has been called),
+// so don't call them while another goroutine is calling CheckForMessages
+
+import (
+	"context" // For cancelling and timing out
+	"time"    // For read deadlines and retry intervals
+
+	"github.com/Grayda/go-orvibo/packet" // For our discovery broadcast and command IDs
+)
+
+// CheckForMessagesContext is CheckForMessages, but gives up when ctx is done, returning ctx.Err()
+func (c *Client) CheckForMessagesContext(ctx context.Context) (bool, error) {
+	if err := c.checkReady(); err != nil {
+		return false, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	// Cut the read short when ctx is done. A deadline in the past makes a blocked read return straight away
+	finished := make(chan bool)
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.conn.SetReadDeadline(time.Now())
+		case <-finished:
+		}
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetReadDeadline(deadline)
+	}
+
+	success, err := c.CheckForMessages()
+	c.conn.SetReadDeadline(time.Time{}) // Back to blocking reads for everyone else
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return false, ctxErr
+	}
+
+	return success, err
+}
+
+// DiscoverContext broadcasts a discovery message, then reads replies until ctx is done. Returns every device we
+// heard from in that time. Running out of time is how discovery normally finishes, so that isn't an error
+func (c *Client) DiscoverContext(ctx context.Context) ([]*Device, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	c.PurgeStaleDevices()
+	if _, err := c.broadcastMessage(packet.DiscoverAll); err != nil {
+		return nil, err
+	}
+
+	c.passMessage("discover", &Device{})
+	for ctx.Err() == nil {
+		if err := c.pumpMessages(ctx, waitPollInterval); err != nil { // Closed while we were waiting
+			return nil, err
+		}
+	}
+
+	var found []*Device
+	for _, device := range c.GetDevices() {
+		if device.LastSeen.Before(start) == false {
+			found = append(found, device)
+		}
+	}
+
+	return found, nil
+}
+
+// SubscribeContext subscribes to a single device, and waits for it to confirm. Asks again (waiting twice as long each
+// time, like Acknowledged) until it does, or until ctx is done, in which case ctx.Err() is returned
+func (c *Client) SubscribeContext(ctx context.Context, macAdd string) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err = c.untilAcknowledged(ctx, device, packet.Subscribe, nil, func() (bool, error) {
+		return c.subscribeDevice(device)
+	})
+	return err
+}
+
+// SetStateContext sets the state of a socket, and waits for the socket to confirm it with a state change (7366).
+// The command is always sent, even if we think the socket is already in that state. It's sent again (waiting twice
+// as long each time, like Acknowledged) until the socket confirms it, or until ctx is done, in which case ctx.Err()
+// is returned. Dry runs don't wait
+func (c *Client) SetStateContext(ctx context.Context, macAdd string, state bool, opts ...CommandOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := c.SetState(macAdd, state, append(opts, acknowledgedUntil(ctx))...)
+	return err
+}
+
+// SetStateSync sets the state of a socket, and blocks until the socket confirms it. If it hasn't confirmed within
+// timeout, ErrNoAck is returned. Like SetStateContext, the command is sent again until then
+func (c *Client) SetStateSync(macAdd string, state bool, timeout time.Duration, opts ...CommandOption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := c.SetStateContext(ctx, macAdd, state, opts...)
+	if err == context.DeadlineExceeded {
+		return ErrNoAck
+	}
+
+	return err
+}
@@ -0,0 +1,32 @@
+package orvibo
+
+// Sockets have an auto-off countdown. Its status and length are the last four bytes of the socket's table 4
+// record, which we get back when we query it. This hasn't been tested against every firmware, so if your
+// countdown looks wrong, please pass on your LastMessage to the developer!
+
+import (
+	"time" // For durations and timestamps
+
+	"github.com/Grayda/go-orvibo/packet" // For reading the countdown out of the record
+)
+
+// parseCountdown reads the countdown out of a table 4 query response and stores it on the device. Hold devicesLock while calling it
+func parseCountdown(device *Device, message string) {
+	remaining, ok := packet.Countdown(message)
+	if ok == false { // Older firmware may not send the countdown at all
+		return
+	}
+
+	device.CountdownRemaining = remaining
+	device.CountdownUpdated = time.Now()
+}
+
+// CountdownEnds works out when a socket's countdown will finish, based on when we last queried it.
+// Returns false if there's no countdown running
+func CountdownEnds(device *Device) (time.Time, bool) {
+	if device.CountdownRemaining <= 0 {
+		return time.Time{}, false
+	}
+
+	return device.CountdownUpdated.Add(device.CountdownRemaining), true
+}
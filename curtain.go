@@ -0,0 +1,181 @@
+package orvibo
+
+// RF curtain motors and roller blinds. Orvibo's 433MHz curtain motors are driven through the AllOne, like RF
+// switches, but their remotes have separate open, close and stop buttons, each with its own code. Learn each
+// button with LearnCurtainCode (or set codes you already know with SetCurtainCodes), then use OpenCurtain,
+// CloseCurtain and StopCurtain.
+//
+// LearnCurtainCode shares its RF learning with PairRFSwitch, so it depends on ExperimentalRF (see learn.go) the
+// same way. Codes set with SetCurtainCodes don't go through learning, so they work without it
+
+import (
+	"encoding/hex" // For checking RF codes
+	"fmt"          // For building our error messages
+	"time"         // For our learning timeout
+)
+
+// CurtainAction is something a curtain can be told to do
+type CurtainAction string
+
+// The buttons on a curtain's remote
+const (
+	CurtainOpen  CurtainAction = "open"
+	CurtainClose CurtainAction = "close"
+	CurtainStop  CurtainAction = "stop"
+)
+
+// CurtainEvent is the Payload of a "curtain" event
+type CurtainEvent struct {
+	Device    *Device // The AllOne that sent the command
+	CurtainID string
+	Action    CurtainAction
+}
+
+// LearnCurtainCode puts the AllOne with the MAC address allOneMAC into RF learning mode, waits for you to press
+// the action button on the curtain's remote, then stores the code in Curtains[curtainID] (curtainID is whatever
+// you'd like to call it). If nothing's heard within timeout, ErrLearnTimeout is returned. Like PairRFSwitch, it
+// reads messages itself unless Listen has been called. Experimental: returns ErrExperimentalRF unless
+// ExperimentalRF is set
+func (c *Client) LearnCurtainCode(allOneMAC string, curtainID string, action CurtainAction, timeout time.Duration) (Curtain, error) {
+	if err := checkCurtainAction(action); err != nil {
+		return Curtain{}, err
+	}
+
+	device, code, err := c.learnRF(allOneMAC, timeout)
+	if err != nil {
+		return Curtain{}, err
+	}
+
+	c.devicesLock.Lock()
+	defer c.devicesLock.Unlock()
+	return storeCurtainCode(device, curtainID, action, code), nil
+}
+
+// SetCurtainCodes stores the open, close and stop codes (as hex) for the curtain curtainID on the AllOne with the
+// MAC address allOneMAC, for when you already know them and don't need to learn them. Empty codes are left as they are
+func (c *Client) SetCurtainCodes(allOneMAC string, curtainID string, openCode string, closeCode string, stopCode string) (Curtain, error) {
+	device, err := c.knownDevice(allOneMAC)
+	if err != nil {
+		return Curtain{}, err
+	}
+
+	if device.DeviceType != ALLONE {
+		return Curtain{}, fmt.Errorf("%s isn't an AllOne, so it can't control curtains", allOneMAC)
+	}
+
+	codes := map[CurtainAction]string{CurtainOpen: openCode, CurtainClose: closeCode, CurtainStop: stopCode}
+	for _, code := range codes {
+		if _, err := hex.DecodeString(code); err != nil {
+			return Curtain{}, fmt.Errorf("%q isn't an RF code. It should be a hex string, like 2b00daaeeb", code)
+		}
+	}
+
+	c.devicesLock.Lock()
+	defer c.devicesLock.Unlock()
+
+	for action, code := range codes {
+		if code != "" {
+			storeCurtainCode(device, curtainID, action, code)
+		}
+	}
+
+	return device.Curtains[curtainID], nil
+}
+
+// OpenCurtain opens the curtain curtainID, via the AllOne with the MAC address allOneMAC
+func (c *Client) OpenCurtain(allOneMAC string, curtainID string, opts ...CommandOption) error {
+	return c.moveCurtain(allOneMAC, curtainID, CurtainOpen, getCommandOptions(opts))
+}
+
+// CloseCurtain closes the curtain curtainID, via the AllOne with the MAC address allOneMAC
+func (c *Client) CloseCurtain(allOneMAC string, curtainID string, opts ...CommandOption) error {
+	return c.moveCurtain(allOneMAC, curtainID, CurtainClose, getCommandOptions(opts))
+}
+
+// StopCurtain stops the curtain curtainID where it is, via the AllOne with the MAC address allOneMAC
+func (c *Client) StopCurtain(allOneMAC string, curtainID string, opts ...CommandOption) error {
+	return c.moveCurtain(allOneMAC, curtainID, CurtainStop, getCommandOptions(opts))
+}
+
+// moveCurtain sends the code for action to the curtain curtainID. Once the command's gone out, LastAction
+// is updated and "curtain" is raised
+func (c *Client) moveCurtain(allOneMAC string, curtainID string, action CurtainAction, o commandOptions) error {
+	device, err := c.knownDevice(allOneMAC)
+	if err != nil {
+		return err
+	}
+
+	c.devicesLock.RLock()
+	curtain, found := device.Curtains[curtainID]
+	c.devicesLock.RUnlock()
+
+	if found == false {
+		return fmt.Errorf("%s doesn't know about a curtain called %q. Use LearnCurtainCode first", allOneMAC, curtainID)
+	}
+
+	code := curtain.code(action)
+	if code == "" {
+		return fmt.Errorf("%s hasn't learned the %s code for %q. Use LearnCurtainCode first", allOneMAC, action, curtainID)
+	}
+
+	// Each button has its own code, so the code says what to do, not the state. We just send "on" for all of them
+	if err := c.emitRF(true, code, allOneMAC, o); err != nil {
+		return err
+	}
+
+	if o.dryRun { // Nothing was sent, so nothing's changed
+		return nil
+	}
+
+	c.devicesLock.Lock()
+	curtain.LastAction = action
+	device.Curtains[curtainID] = curtain
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passEvent("curtain", snapshot, CurtainEvent{Device: snapshot, CurtainID: curtainID, Action: action})
+	return nil
+}
+
+// code returns the curtain's code for action, or "" if it hasn't been learned
+func (curtain Curtain) code(action CurtainAction) string {
+	switch action {
+	case CurtainOpen:
+		return curtain.OpenCode
+	case CurtainClose:
+		return curtain.CloseCode
+	case CurtainStop:
+		return curtain.StopCode
+	}
+
+	return ""
+}
+
+// checkCurtainAction makes sure action is one of the buttons a curtain has
+func checkCurtainAction(action CurtainAction) error {
+	if action != CurtainOpen && action != CurtainClose && action != CurtainStop {
+		return fmt.Errorf("%q isn't something a curtain can do. Use CurtainOpen, CurtainClose or CurtainStop", action)
+	}
+
+	return nil
+}
+
+// storeCurtainCode saves code as the curtain's code for action, and returns the updated curtain. Hold devicesLock while calling it
+func storeCurtainCode(device *Device, curtainID string, action CurtainAction, code string) Curtain {
+	if device.Curtains == nil { // In case the Device was made by hand
+		device.Curtains = make(map[string]Curtain)
+	}
+
+	curtain := device.Curtains[curtainID]
+	switch action {
+	case CurtainOpen:
+		curtain.OpenCode = code
+	case CurtainClose:
+		curtain.CloseCode = code
+	case CurtainStop:
+		curtain.StopCode = code
+	}
+
+	device.Curtains[curtainID] = curtain
+	return curtain
+}
@@ -0,0 +1,438 @@
+package orvibo
+
+// The package-level functions. These all use a default Client, so programs that only need one controller
+// don't have to create one. The package-level Devices, Events and settings variables belong to it. Devices
+// and Events are shared with it, but the settings are only copied over by Prepare
+
+import (
+	"context" // For cancelling and timing out
+	"io"      // For exports
+	"sync"    // For locking the default client
+	"time"    // For intervals
+)
+
+var defaultClient = newDefaultClient() // The Client our package-level functions use
+var stdLock sync.Mutex                 // Close replaces defaultClient, and Prepare brings it up to date, maybe from another goroutine
+
+// newDefaultClient makes a Client that shares the package-level Devices, Events and ArchivedDevices
+func newDefaultClient() *Client {
+	c := NewClient()
+	c.Devices = Devices
+	c.Events = Events
+	c.ArchivedDevices = ArchivedDevices
+	return c
+}
+
+// std returns the default Client
+func std() *Client {
+	stdLock.Lock()
+	defer stdLock.Unlock()
+	return defaultClient
+}
+
+// Prepare is the first function you should call. Gets our UDP connection ready. The package-level settings
+// variables (e.g. AutoSubscribe) are copied into the default client here, once, so set them before calling
+// Prepare. Changing them afterwards does nothing until Close and Prepare are called again. Options that have a
+// package-level variable are overwritten by it, so set those with the variable instead
+func Prepare(opts ...ClientOption) (bool, error) {
+	stdLock.Lock()
+	c := defaultClient
+	if c.checkReady() != nil { // Don't touch a client that's already running
+		c.Devices = Devices
+		c.Events = Events
+		c.AutoSubscribe = AutoSubscribe
+		c.AutoQuery = AutoQuery
+		c.AutoResubscribe = AutoResubscribe
+		c.RawStateEvents = RawStateEvents
+		c.Passive = Passive
+		c.ReceiveBufferSize = ReceiveBufferSize
+		c.DeviceTTL = DeviceTTL
+		c.ArchivePurged = ArchivePurged
+		c.ArchivedDevices = ArchivedDevices
+		c.AllowedOUIs = AllowedOUIs
+		c.BroadcastInterface = BroadcastInterface
+	}
+	stdLock.Unlock()
+
+	return c.Prepare(opts...)
+}
+
+// Close shuts down our UDP connection and background listener, and closes Events. Afterwards, the default
+// client starts again from scratch, with new (empty) Devices and Events, so Prepare can be called again.
+// Callbacks registered with On or Once are forgotten, too
+func Close() error {
+	c := std()
+	if c.checkReady() != nil { // Nothing to close
+		return nil
+	}
+
+	err := c.Close()
+
+	stdLock.Lock()
+	Events = make(chan EventStruct, EventBufferSize)
+	Devices = make(map[string]*Device)
+	ArchivedDevices = make(map[string]*Device)
+	defaultClient = newDefaultClient()
+	stdLock.Unlock()
+	return err
+}
+
+// Discover is a function that broadcasts 686400067161 over the network in order to find unpaired networks
+func Discover() {
+	std().Discover()
+}
+
+// DiscoverDevice looks for one device by its MAC address. Its reply raises the same found events as Discover
+func DiscoverDevice(macAdd string) error {
+	return std().DiscoverDevice(macAdd)
+}
+
+// AddDeviceByIP sends a discovery message straight to ip, rather than broadcasting it
+func AddDeviceByIP(ip string) error {
+	return std().AddDeviceByIP(ip)
+}
+
+// RegisterDevice adds a device to Devices without hearing from it first. deviceType is SOCKET or ALLONE
+func RegisterDevice(macAdd string, ip string, deviceType int) (*Device, error) {
+	return std().RegisterDevice(macAdd, ip, deviceType)
+}
+
+// StartAutoDiscovery calls Discover straight away, then again every interval, until StopAutoDiscovery or Close is called
+func StartAutoDiscovery(interval time.Duration) error {
+	return std().StartAutoDiscovery(interval)
+}
+
+// StopAutoDiscovery stops the automatic discovery started by StartAutoDiscovery
+func StopAutoDiscovery() {
+	std().StopAutoDiscovery()
+}
+
+// Subscribe loops over all the Devices we know about, and asks for control (subscription). Returns the result for each device, keyed by MAC address
+func Subscribe() map[string]error {
+	return std().Subscribe()
+}
+
+// Query asks all the sockets we've subscribed to but haven't queried yet, for their names. Returns the result for each device, keyed by MAC address
+func Query() map[string]error {
+	return std().Query()
+}
+
+// SubscribeDevice subscribes to a single device, rather than everything we know about
+func SubscribeDevice(macAdd string) error {
+	return std().SubscribeDevice(macAdd)
+}
+
+// QueryDevice asks a single device for its details (including its name), whether or not we've queried it before
+func QueryDevice(macAdd string) error {
+	return std().QueryDevice(macAdd)
+}
+
+// ListDevices logs every Device we know about at Info level
+func ListDevices() {
+	std().ListDevices()
+}
+
+// GetDevice returns a copy of the device with the MAC address macAdd, if we know about it
+func GetDevice(macAdd string) (*Device, bool) {
+	return std().GetDevice(macAdd)
+}
+
+// GetDevices returns a copy of every device we know about, keyed by MAC address
+func GetDevices() map[string]*Device {
+	return std().GetDevices()
+}
+
+// CheckForMessages does what it says on the tin -- checks for incoming UDP messages
+func CheckForMessages() (bool, error) {
+	return std().CheckForMessages()
+}
+
+// ToggleState finds out if the socket is on or off, then toggles it
+func ToggleState(macAdd string, opts ...CommandOption) (bool, error) {
+	return std().ToggleState(macAdd, opts...)
+}
+
+// SetState sets the state of a socket, given its MAC address
+func SetState(macAdd string, state bool, opts ...CommandOption) (bool, error) {
+	return std().SetState(macAdd, state, opts...)
+}
+
+// EmitIR emits IR from an AllOne (or every AllOne, if macAdd is "ALL"). Takes a hex string. Returns an error if we can't send it
+func EmitIR(IR string, macAdd string, opts ...CommandOption) error {
+	return std().EmitIR(IR, macAdd, opts...)
+}
+
+// EmitRF switches an RF switch on or off via the AllOne. code is the switch's code, as hex
+func EmitRF(state bool, code string, macAdd string, opts ...CommandOption) error {
+	return std().EmitRF(state, code, macAdd, opts...)
+}
+
+// EnterLearningMode puts the AllOne into IR learning mode
+func EnterLearningMode(macAdd string) error {
+	return std().EnterLearningMode(macAdd)
+}
+
+// EnterRFLearningMode puts the AllOne into RF learning mode
+func EnterRFLearningMode(macAdd string) error {
+	return std().EnterRFLearningMode(macAdd)
+}
+
+// SendMessage is the heart of our library. Sends UDP messages to specified IP addresses
+func SendMessage(msg string, device *Device) (bool, error) {
+	return std().SendMessage(msg, device)
+}
+
+// PurgeStaleDevices removes any devices we haven't heard from in DeviceTTL. Returns the MAC addresses of the devices that were purged
+func PurgeStaleDevices() []string {
+	return std().PurgeStaleDevices()
+}
+
+// Stats counts up all the devices we know about, and works out how quickly they've been answering our commands
+func Stats() FleetStats {
+	return std().Stats()
+}
+
+// DiscoverStream broadcasts a discovery message, then returns a channel that gets every newly found device until ctx is cancelled
+func DiscoverStream(ctx context.Context) <-chan *Device {
+	return std().DiscoverStream(ctx)
+}
+
+// DeviceEvents returns a channel that gets every event raised for one device, until ctx is cancelled
+func DeviceEvents(ctx context.Context, macAdd string) <-chan EventStruct {
+	return std().DeviceEvents(ctx, macAdd)
+}
+
+// WaitForDevice blocks until the device with the given MAC address has been discovered, subscribed to and queried, or ctx is done
+func WaitForDevice(ctx context.Context, macAdd string) (*Device, error) {
+	return std().WaitForDevice(ctx, macAdd)
+}
+
+// ExportHomeAssistant writes out all the sockets we know about as a Home Assistant "orvibo" switch platform config
+func ExportHomeAssistant(w io.Writer) error {
+	return std().ExportHomeAssistant(w)
+}
+
+// ExportOpenHAB writes out all the sockets we know about as openHAB Things and Items for the orvibo binding
+func ExportOpenHAB(things io.Writer, items io.Writer) error {
+	return std().ExportOpenHAB(things, items)
+}
+
+// CheckForMessagesContext is CheckForMessages, but gives up when ctx is done, returning ctx.Err()
+func CheckForMessagesContext(ctx context.Context) (bool, error) {
+	return std().CheckForMessagesContext(ctx)
+}
+
+// DiscoverContext broadcasts a discovery message, then reads replies until ctx is done. Returns every device we heard from in that time
+func DiscoverContext(ctx context.Context) ([]*Device, error) {
+	return std().DiscoverContext(ctx)
+}
+
+// SubscribeContext subscribes to a single device, and waits for it to confirm, or for ctx to be done
+func SubscribeContext(ctx context.Context, macAdd string) error {
+	return std().SubscribeContext(ctx, macAdd)
+}
+
+// SetStateContext sets the state of a socket, and waits for the socket to confirm it, or for ctx to be done
+func SetStateContext(ctx context.Context, macAdd string, state bool, opts ...CommandOption) error {
+	return std().SetStateContext(ctx, macAdd, state, opts...)
+}
+
+// SetStateSync sets the state of a socket, and blocks until the socket confirms it. Returns ErrNoAck if it doesn't within timeout
+func SetStateSync(macAdd string, state bool, timeout time.Duration, opts ...CommandOption) error {
+	return std().SetStateSync(macAdd, state, timeout, opts...)
+}
+
+// Listen starts reading messages on a background goroutine, until Stop or Close is called
+func Listen() error {
+	return std().Listen()
+}
+
+// Stop stops the background listener started by Listen, and waits for it to finish
+func Stop() {
+	std().Stop()
+}
+
+// GetTimers reads the timers off a socket
+func GetTimers(macAdd string) ([]Timer, error) {
+	return std().GetTimers(macAdd)
+}
+
+// SetTimer adds a timer to a socket (if its ID is 0) or changes an existing one
+func SetTimer(macAdd string, timer Timer) error {
+	return std().SetTimer(macAdd, timer)
+}
+
+// DeleteTimer removes a timer from a socket
+func DeleteTimer(macAdd string, id int) error {
+	return std().DeleteTimer(macAdd, id)
+}
+
+// SetDeviceName renames a device, and raises "namechanged" once the device has accepted it
+func SetDeviceName(macAdd string, name string) error {
+	return std().SetDeviceName(macAdd, name)
+}
+
+// SetRemotePassword changes the password the WiWo app uses to control a device from outside your network
+func SetRemotePassword(macAdd string, password string) error {
+	return std().SetRemotePassword(macAdd, password)
+}
+
+// SetTimezone sets a device's timezone, as hours from UTC
+func SetTimezone(macAdd string, hours int) error {
+	return std().SetTimezone(macAdd, hours)
+}
+
+// SetDiscoverable sets whether a device answers discovery broadcasts
+func SetDiscoverable(macAdd string, discoverable bool) error {
+	return std().SetDiscoverable(macAdd, discoverable)
+}
+
+// On calls handler every time the named event (e.g. "statechanged") is raised
+func On(name string, handler EventHandler) {
+	std().On(name, handler)
+}
+
+// Once calls handler the next time the named event is raised, and then forgets about it
+func Once(name string, handler EventHandler) {
+	std().Once(name, handler)
+}
+
+// Off removes every callback registered for the named event
+func Off(name string) {
+	std().Off(name)
+}
+
+// DroppedEvents returns how many events have been dropped because nobody was reading Events fast enough
+func DroppedEvents() uint64 {
+	return std().DroppedEvents()
+}
+
+// EmitIRByName looks up an IR code in the library, and emits it from an AllOne (or every AllOne, if macAdd is "ALL")
+func EmitIRByName(name string, macAdd string, opts ...CommandOption) error {
+	return std().EmitIRByName(name, macAdd, opts...)
+}
+
+// LearnIR puts an AllOne into learning mode, waits for it to hear a code, and returns it. Returns ErrLearnTimeout if nothing's heard within timeout
+func LearnIR(macAdd string, timeout time.Duration) (string, error) {
+	return std().LearnIR(macAdd, timeout)
+}
+
+// EmitIRNEC builds an NEC code and emits it from an AllOne (or every AllOne, if macAdd is "ALL"). Returns ErrExperimentalIR unless ExperimentalIR is set
+func EmitIRNEC(address uint16, command uint8, macAdd string, opts ...CommandOption) error {
+	return std().EmitIRNEC(address, command, macAdd, opts...)
+}
+
+// EmitIRRC5 builds an RC5 code and emits it from an AllOne (or every AllOne, if macAdd is "ALL"). Returns ErrExperimentalIR unless ExperimentalIR is set
+func EmitIRRC5(address uint8, command uint8, toggle bool, macAdd string, opts ...CommandOption) error {
+	return std().EmitIRRC5(address, command, toggle, macAdd, opts...)
+}
+
+// EmitIRSequence emits each of codes from an AllOne, waiting gap between each one. It blocks until the sequence is finished
+func EmitIRSequence(macAdd string, codes []IRCode, gap time.Duration, opts ...CommandOption) error {
+	return std().EmitIRSequence(macAdd, codes, gap, opts...)
+}
+
+// PairRFSwitch puts an AllOne into RF learning mode, waits for an RF switch to be pressed, and stores its code as switchID
+func PairRFSwitch(macAdd string, switchID string, timeout time.Duration) (RFSwitch, error) {
+	return std().PairRFSwitch(macAdd, switchID, timeout)
+}
+
+// SetRFState turns a paired RF switch on or off, via an AllOne
+func SetRFState(allOneMAC string, switchID string, state bool, opts ...CommandOption) error {
+	return std().SetRFState(allOneMAC, switchID, state, opts...)
+}
+
+// LearnCurtainCode puts an AllOne into RF learning mode, waits for a button on a curtain's remote, and stores its code as action for curtainID
+func LearnCurtainCode(allOneMAC string, curtainID string, action CurtainAction, timeout time.Duration) (Curtain, error) {
+	return std().LearnCurtainCode(allOneMAC, curtainID, action, timeout)
+}
+
+// SetCurtainCodes stores the open, close and stop codes for a curtain, for when you already know them
+func SetCurtainCodes(allOneMAC string, curtainID string, openCode string, closeCode string, stopCode string) (Curtain, error) {
+	return std().SetCurtainCodes(allOneMAC, curtainID, openCode, closeCode, stopCode)
+}
+
+// OpenCurtain opens a curtain, via an AllOne
+func OpenCurtain(allOneMAC string, curtainID string, opts ...CommandOption) error {
+	return std().OpenCurtain(allOneMAC, curtainID, opts...)
+}
+
+// CloseCurtain closes a curtain, via an AllOne
+func CloseCurtain(allOneMAC string, curtainID string, opts ...CommandOption) error {
+	return std().CloseCurtain(allOneMAC, curtainID, opts...)
+}
+
+// StopCurtain stops a curtain where it is, via an AllOne
+func StopCurtain(allOneMAC string, curtainID string, opts ...CommandOption) error {
+	return std().StopCurtain(allOneMAC, curtainID, opts...)
+}
+
+// StartHealthMonitor checks on every device every interval, raising "deviceoffline" and "deviceonline" as they come and go
+func StartHealthMonitor(interval time.Duration) error {
+	return std().StartHealthMonitor(interval)
+}
+
+// StopHealthMonitor stops the health monitor started by StartHealthMonitor
+func StopHealthMonitor() {
+	std().StopHealthMonitor()
+}
+
+// SaveDevices writes the devices we know about to a JSON file, for LoadDevices to read back in later
+func SaveDevices(path string) error {
+	return std().SaveDevices(path)
+}
+
+// LoadDevices reads devices saved by SaveDevices, and subscribes to any we don't already know about at their saved addresses
+func LoadDevices(path string) error {
+	return std().LoadDevices(path)
+}
+
+// CreateGroup makes a group called name, holding the devices with the MAC addresses macs
+func CreateGroup(name string, macs ...string) error {
+	return std().CreateGroup(name, macs...)
+}
+
+// DeleteGroup removes the group called name
+func DeleteGroup(name string) {
+	std().DeleteGroup(name)
+}
+
+// GroupMembers returns the MAC addresses of the devices in the group called name
+func GroupMembers(name string) ([]string, bool) {
+	return std().GroupMembers(name)
+}
+
+// Groups returns the names of every group, in alphabetical order
+func Groups() []string {
+	return std().Groups()
+}
+
+// SetGroupState turns every socket in a group on or off
+func SetGroupState(name string, state bool, opts ...CommandOption) (map[string]error, error) {
+	return std().SetGroupState(name, state, opts...)
+}
+
+// EmitIRToGroup emits an IR code from every AllOne in a group
+func EmitIRToGroup(IR string, name string, opts ...CommandOption) (map[string]error, error) {
+	return std().EmitIRToGroup(IR, name, opts...)
+}
+
+// RemoveDevice forgets about a device, raising "deviceremoved"
+func RemoveDevice(macAdd string) error {
+	return std().RemoveDevice(macAdd)
+}
+
+// OnRawPacket calls handler with every packet we send or receive
+func OnRawPacket(handler RawPacketHandler) {
+	std().OnRawPacket(handler)
+}
+
+// OffRawPacket removes every handler registered with OnRawPacket
+func OffRawPacket() {
+	std().OffRawPacket()
+}
+
+// ReplayPCAP passes every Orvibo packet in a pcap capture through the message handler, raising the same events we would have live
+func ReplayPCAP(path string) (int, error) {
+	return std().ReplayPCAP(path)
+}
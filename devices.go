@@ -0,0 +1,94 @@
+package orvibo
+
+// Locking our devices. The listener changes devices as their messages come in, while the calling code, and our own
+// background jobs (auto discovery, resubscription, the health monitor), look at and change them on other goroutines.
+// devicesLock covers Devices, ArchivedDevices, deviceCount and the fields of every Device in them.
+//
+// Hold it for as short a time as possible, and never while raising an event or sending a packet: callbacks run on
+// the listener, and might want to look at a device themselves. Events (and discovery streams) carry a copy of their
+// device, taken while the lock was held, so the calling code can read them on whatever goroutine it likes
+
+// GetDevice returns a copy of the device with the MAC address macAdd, if we know about it. Use it (or GetDevices)
+// rather than reading Devices while Listen, StartAutoDiscovery and the like are running
+func (c *Client) GetDevice(macAdd string) (*Device, bool) {
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+
+	device, found := c.Devices[macAdd]
+	if found == false {
+		return nil, false
+	}
+
+	return device.copy(), true
+}
+
+// GetDevices returns a copy of every device we know about, keyed by MAC address
+func (c *Client) GetDevices() map[string]*Device {
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+
+	devices := make(map[string]*Device, len(c.Devices))
+	for macAdd, device := range c.Devices {
+		devices[macAdd] = device.copy()
+	}
+
+	return devices
+}
+
+// device looks up the device with the MAC address macAdd. It's the live Device, so lock before reading anything
+// that can change
+func (c *Client) device(macAdd string) (*Device, bool) {
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+
+	device, found := c.Devices[macAdd]
+	return device, found
+}
+
+// deviceList returns every device we know about, so we can go through them without holding the lock the whole time.
+// Like device, these are the live Devices
+func (c *Client) deviceList() []*Device {
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+
+	devices := make([]*Device, 0, len(c.Devices))
+	for _, device := range c.Devices {
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// snapshot takes a copy of device, for an event or anything else that leaves this goroutine
+func (c *Client) snapshot(device *Device) *Device {
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+
+	return device.copy()
+}
+
+// copy returns a copy of device with its own RFSwitches, Curtains and Timers, so changes to one don't show up in
+// the other. Hold devicesLock while calling it
+func (device *Device) copy() *Device {
+	out := *device
+
+	if device.RFSwitches != nil {
+		out.RFSwitches = make(map[string]RFSwitch, len(device.RFSwitches))
+		for id, rfSwitch := range device.RFSwitches {
+			out.RFSwitches[id] = rfSwitch
+		}
+	}
+
+	if device.Curtains != nil {
+		out.Curtains = make(map[string]Curtain, len(device.Curtains))
+		for id, curtain := range device.Curtains {
+			out.Curtains[id] = curtain
+		}
+	}
+
+	if device.Timers != nil {
+		out.Timers = append([]Timer(nil), device.Timers...)
+	}
+
+	return &out
+}
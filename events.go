@@ -0,0 +1,201 @@
+package orvibo
+
+// Typed events. Every event we raise has a Type, so calling code can switch on EventStateChanged instead of
+// matching on "statechanged" and hoping it's spelled right. Some events also carry a Payload with the details
+// (e.g. what the state was before it changed). Name is still filled in, so code written against the old string
+// events keeps working
+
+// EventType says what kind of event an EventStruct is
+type EventType int
+
+// All the events we raise. The comment after each is the legacy Name it goes out with
+const (
+	EventUnknown              EventType = iota // Something we don't have a type for
+	EventReady                                 // ready
+	EventClosed                                // closed
+	EventListening                             // listening
+	EventStopped                               // stopped
+	EventDiscover                              // discover
+	EventSubscribe                             // subscribe
+	EventQuery                                 // query
+	EventBroadcast                             // broadcast
+	EventSendMessage                           // sendmessage
+	EventMessageTruncated                      // messagetruncated
+	EventSocketFound                           // socketfound
+	EventAllOneFound                           // allonefound
+	EventExistingSocketFound                   // existingsocketfound
+	EventExistingAllOneFound                   // existingallonefound
+	EventUnknownHardwareFound                  // unknownhardwarefound
+	EventSubscribed                            // subscribed
+	EventQueried                               // queried
+	EventDeviceReady                           // deviceready
+	EventDeviceRebooted                        // devicerebooted
+	EventDevicePurged                          // devicepurged
+	EventStateSet                              // stateset
+	EventStateChanged                          // statechanged
+	EventExternalStateSet                      // externalstateset
+	EventVirtualStateChanged                   // virtualstatechanged
+	EventButtonPress                           // buttonpress
+	EventIRLearnMode                           // irlearnmode
+	EventRFLearnMode                           // rflearnmode
+	EventIRCode                                // ircode
+	EventRFSwitch                              // rfswitch
+	EventTimers                                // timers
+	EventNameChanged                           // namechanged
+	EventQuietWindow                           // quietwindow
+	EventBreakerTripped                        // breakertripped
+	EventBreakerClosed                         // breakerclosed
+	EventAckTimeout                            // acktimeout
+	EventHandlerPanic                          // handlerpanic
+	EventEventsDropped                         // eventsdropped
+	EventSubscriptionRenewed                   // subscriptionrenewed
+	EventSubscriptionLost                      // subscriptionlost
+	EventIRSequenceProgress                    // irsequenceprogress
+	EventIRSequenceDone                        // irsequencedone
+	EventRFCode                                // rfcode
+	EventRFSwitchPaired                        // rfswitchpaired
+	EventRFStateChanged                        // rfstatechanged
+	EventCurtain                               // curtain
+	EventDeviceOffline                         // deviceoffline
+	EventDeviceOnline                          // deviceonline
+	EventDeviceRemoved                         // deviceremoved
+	EventDeviceIPChanged                       // deviceipchanged
+	EventParseError                            // parseerror
+	EventListenerError                         // listenererror
+)
+
+// eventNames are the legacy names for each EventType
+var eventNames = map[EventType]string{
+	EventReady:                "ready",
+	EventClosed:               "closed",
+	EventListening:            "listening",
+	EventStopped:              "stopped",
+	EventDiscover:             "discover",
+	EventSubscribe:            "subscribe",
+	EventQuery:                "query",
+	EventBroadcast:            "broadcast",
+	EventSendMessage:          "sendmessage",
+	EventMessageTruncated:     "messagetruncated",
+	EventSocketFound:          "socketfound",
+	EventAllOneFound:          "allonefound",
+	EventExistingSocketFound:  "existingsocketfound",
+	EventExistingAllOneFound:  "existingallonefound",
+	EventUnknownHardwareFound: "unknownhardwarefound",
+	EventSubscribed:           "subscribed",
+	EventQueried:              "queried",
+	EventDeviceReady:          "deviceready",
+	EventDeviceRebooted:       "devicerebooted",
+	EventDevicePurged:         "devicepurged",
+	EventStateSet:             "stateset",
+	EventStateChanged:         "statechanged",
+	EventExternalStateSet:     "externalstateset",
+	EventVirtualStateChanged:  "virtualstatechanged",
+	EventButtonPress:          "buttonpress",
+	EventIRLearnMode:          "irlearnmode",
+	EventRFLearnMode:          "rflearnmode",
+	EventIRCode:               "ircode",
+	EventRFSwitch:             "rfswitch",
+	EventTimers:               "timers",
+	EventNameChanged:          "namechanged",
+	EventQuietWindow:          "quietwindow",
+	EventBreakerTripped:       "breakertripped",
+	EventBreakerClosed:        "breakerclosed",
+	EventAckTimeout:           "acktimeout",
+	EventHandlerPanic:         "handlerpanic",
+	EventEventsDropped:        "eventsdropped",
+	EventSubscriptionRenewed:  "subscriptionrenewed",
+	EventSubscriptionLost:     "subscriptionlost",
+	EventIRSequenceProgress:   "irsequenceprogress",
+	EventIRSequenceDone:       "irsequencedone",
+	EventRFCode:               "rfcode",
+	EventRFSwitchPaired:       "rfswitchpaired",
+	EventRFStateChanged:       "rfstatechanged",
+	EventCurtain:              "curtain",
+	EventDeviceOffline:        "deviceoffline",
+	EventDeviceOnline:         "deviceonline",
+	EventDeviceRemoved:        "deviceremoved",
+	EventDeviceIPChanged:      "deviceipchanged",
+	EventParseError:           "parseerror",
+	EventListenerError:        "listenererror",
+}
+
+// eventTypes is eventNames the other way around, so we can find the type of a legacy name
+var eventTypes = make(map[string]EventType)
+
+func init() {
+	for eventType, name := range eventNames {
+		eventTypes[name] = eventType
+	}
+}
+
+// String returns the legacy name of the event (e.g. "statechanged"), or "unknown" if it hasn't got one
+func (t EventType) String() string {
+	if name, found := eventNames[t]; found {
+		return name
+	}
+
+	return "unknown"
+}
+
+// eventType looks up the type of a legacy event name. Names we don't know are EventUnknown
+func eventType(name string) EventType {
+	return eventTypes[name] // Missing names give us the zero value, EventUnknown
+}
+
+// StateChangedEvent is the Payload of a "statechanged" event
+type StateChangedEvent struct {
+	Device   *Device
+	OldState bool // What we thought the state was before the device told us otherwise
+	NewState bool
+}
+
+// IRLearnedEvent is the Payload of an "ircode" event
+type IRLearnedEvent struct {
+	Device *Device
+	Code   string // The IR code we learned, as a hex string. Pass it straight to EmitIR
+}
+
+// RFLearnedEvent is the Payload of an "rfcode" event
+type RFLearnedEvent struct {
+	Device *Device // The AllOne that learned the code
+	Code   string  // The RF code, as a hex string. Where it sits in the reply is a guess (see ExperimentalRF)
+}
+
+// RFSwitchEvent is the Payload of the "rfswitch" and "rfswitchpaired" events
+type RFSwitchEvent struct {
+	Device   *Device // The AllOne that heard the switch
+	SwitchID string
+	State    bool
+}
+
+// DeviceFoundEvent is the Payload of the "socketfound", "allonefound" and "existing..." events
+type DeviceFoundEvent struct {
+	Device   *Device
+	Existing bool // True if we already knew about it
+}
+
+// DeviceIPChangedEvent is the Payload of a "deviceipchanged" event
+type DeviceIPChangedEvent struct {
+	Device *Device
+	OldIP  string // Where the device used to be. Empty if we didn't have an address for it
+	NewIP  string
+}
+
+// ParseErrorEvent is the Payload of a "parseerror" event
+type ParseErrorEvent struct {
+	Message string // The message we couldn't handle, as hex
+	From    string // Who sent it
+	Reason  string // What was wrong with it
+}
+
+// ListenerErrorEvent is the Payload of a "listenererror" event
+type ListenerErrorEvent struct {
+	Error    string // What went wrong reading from our connection
+	Failures int    // How many reads in a row have failed. The listener waits longer after each one
+}
+
+// EventsDroppedEvent is the Payload of an "eventsdropped" event
+type EventsDroppedEvent struct {
+	Dropped uint64 // How many events were dropped since the last "eventsdropped"
+	Total   uint64 // How many have been dropped altogether
+}
@@ -0,0 +1,99 @@
+package orvibo
+
+// Exporters that turn the devices we've discovered into configuration for other
+// home automation software, for people who'd rather paste in static config than
+// run a bridge.
+
+import (
+	"fmt"     // For writing out our config
+	"io"      // So we can write to files, stdout, HTTP responses etc.
+	"sort"    // For sorting our MAC addresses so the output is always in the same order
+	"strings" // For string manipulation
+)
+
+// ExportHomeAssistant writes out all the sockets we know about as a Home Assistant
+// "orvibo" switch platform config, ready to paste into configuration.yaml.
+// Home Assistant's orvibo platform only handles the S10 / S20, so AllOnes are skipped
+func (c *Client) ExportHomeAssistant(w io.Writer) error {
+	var out []string
+
+	out = append(out, "switch:")
+	out = append(out, "  - platform: orvibo")
+	out = append(out, "    discovery: false")
+	out = append(out, "    switches:")
+
+	devices := c.GetDevices()
+	for _, macAdd := range sortedMACs(devices) {
+		device := devices[macAdd]
+		if device.DeviceType != SOCKET || device.IP == nil { // Only sockets, and only ones we can actually reach
+			continue
+		}
+
+		out = append(out, "      - host: "+device.IP.IP.String())
+		out = append(out, "        mac: "+formatMAC(macAdd))
+		out = append(out, fmt.Sprintf("        name: %q", exportName(device)))
+	}
+
+	_, err := io.WriteString(w, strings.Join(out, "\n")+"\n")
+	return err
+}
+
+// ExportOpenHAB writes out all the sockets we know about as openHAB Things and Items for the
+// orvibo binding. things gets the .things file, items gets the .items file. Each socket gets
+// a Switch item bound to the "power" channel of its S20 thing
+func (c *Client) ExportOpenHAB(things io.Writer, items io.Writer) error {
+	var thingLines, itemLines []string
+
+	devices := c.GetDevices()
+	for _, macAdd := range sortedMACs(devices) {
+		device := devices[macAdd]
+		if device.DeviceType != SOCKET { // The openHAB binding only knows about the S20
+			continue
+		}
+
+		thingUID := "orvibo:s20:" + macAdd
+		thingLines = append(thingLines, fmt.Sprintf("Thing %s %q [ deviceId=%q ]", thingUID, exportName(device), strings.ToUpper(macAdd)))
+		itemLines = append(itemLines, fmt.Sprintf("Switch Orvibo_%s %q { channel=%q }", macAdd, exportName(device), thingUID+":power"))
+	}
+
+	if _, err := io.WriteString(things, strings.Join(thingLines, "\n")+"\n"); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(items, strings.Join(itemLines, "\n")+"\n")
+	return err
+}
+
+// sortedMACs returns the MAC addresses of devices in order, so exports don't shuffle around between runs
+func sortedMACs(devices map[string]*Device) []string {
+	var macs []string
+	for k := range devices {
+		macs = append(macs, k)
+	}
+
+	sort.Strings(macs)
+	return macs
+}
+
+// exportName gives us a usable name for a device, even if we haven't queried it yet
+func exportName(device *Device) string {
+	if device.Name != "" {
+		return device.Name
+	}
+
+	if device.DeviceType == SOCKET {
+		return "Socket " + device.MACAddress
+	}
+
+	return "AllOne " + device.MACAddress
+}
+
+// formatMAC turns accf23aabbcc into ac:cf:23:aa:bb:cc, which is what most other software expects
+func formatMAC(mac string) string {
+	var parts []string
+	for i := 0; i+2 <= len(mac); i += 2 {
+		parts = append(parts, mac[i:i+2])
+	}
+
+	return strings.Join(parts, ":")
+}
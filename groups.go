@@ -0,0 +1,108 @@
+package orvibo
+
+// Device groups. "ALL" lets you send to every AllOne at once, but most houses want something in between, like
+// every socket in the lounge. CreateGroup gives a set of devices a name, and the group commands send to each of them
+
+import (
+	"errors" // For crafting our own errors
+	"fmt"    // For building our error messages
+	"sort"   // For listing groups in order
+)
+
+// CreateGroup makes a group called name, holding the devices with the MAC addresses macs, replacing any group
+// that already has that name. The devices don't have to have been discovered yet
+func (c *Client) CreateGroup(name string, macs ...string) error {
+	if name == "" {
+		return errors.New("Groups need a name")
+	}
+
+	c.groupsLock.Lock()
+	defer c.groupsLock.Unlock()
+
+	c.groups[name] = append([]string(nil), macs...) // Our own copy, so changing macs later doesn't change the group
+	return nil
+}
+
+// DeleteGroup removes the group called name. The devices in it aren't affected
+func (c *Client) DeleteGroup(name string) {
+	c.groupsLock.Lock()
+	defer c.groupsLock.Unlock()
+
+	delete(c.groups, name)
+}
+
+// GroupMembers returns the MAC addresses of the devices in the group called name
+func (c *Client) GroupMembers(name string) ([]string, bool) {
+	c.groupsLock.Lock()
+	defer c.groupsLock.Unlock()
+
+	macs, found := c.groups[name]
+	return append([]string(nil), macs...), found
+}
+
+// Groups returns the names of every group, in alphabetical order
+func (c *Client) Groups() []string {
+	c.groupsLock.Lock()
+	defer c.groupsLock.Unlock()
+
+	var names []string
+	for name := range c.groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// SetGroupState turns every socket in the group called name on or off. Anything in the group that isn't a socket
+// is skipped. Returns the result for each socket, keyed by MAC address, like Subscribe
+func (c *Client) SetGroupState(name string, state bool, opts ...CommandOption) (map[string]error, error) {
+	macs, found := c.GroupMembers(name)
+	if found == false {
+		return nil, fmt.Errorf("There's no group called %q", name)
+	}
+
+	results := make(map[string]error)
+	for _, macAdd := range macs {
+		device, err := c.knownDevice(macAdd)
+		if err != nil {
+			results[macAdd] = err
+			continue
+		}
+
+		if device.DeviceType != SOCKET {
+			continue
+		}
+
+		_, results[macAdd] = c.SetState(macAdd, state, opts...)
+	}
+
+	return results, nil
+}
+
+// EmitIRToGroup emits the IR code IR from every AllOne in the group called name. Anything in the group that isn't an
+// AllOne is skipped. Returns the result for each AllOne, keyed by MAC address, like Subscribe
+func (c *Client) EmitIRToGroup(IR string, name string, opts ...CommandOption) (map[string]error, error) {
+	macs, found := c.GroupMembers(name)
+	if found == false {
+		return nil, fmt.Errorf("There's no group called %q", name)
+	}
+
+	o := getCommandOptions(opts)
+	results := make(map[string]error)
+	for _, macAdd := range macs {
+		device, err := c.knownDevice(macAdd)
+		if err != nil {
+			results[macAdd] = err
+			continue
+		}
+
+		if device.DeviceType != ALLONE {
+			continue
+		}
+
+		results[macAdd] = c.emitIR(IR, macAdd, o)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,83 @@
+package orvibo_test
+
+import (
+	"strings" // For checking what we sent
+	"testing"
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/packet"
+)
+
+func TestGroups(t *testing.T) {
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	if _, err := c.RegisterDevice(testAllOne, testAddr.IP.String(), orvibo.ALLONE); err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+
+	unknown := "accf00000099" // We haven't heard of this one
+	if err := c.CreateGroup("lounge", testSocket, testAllOne, unknown); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+
+	results, err := c.SetGroupState("lounge", true)
+	if err != nil {
+		t.Fatalf("SetGroupState: %v", err)
+	}
+
+	if len(results) != 2 || results[testSocket] != nil || results[unknown] == nil { // The AllOne isn't a socket, so it's skipped
+		t.Errorf("Got %v, not success for the socket and an error for the unknown device", results)
+	}
+
+	sent := sentWith(transport, packet.StateControl)
+	if len(sent) != 1 || strings.Contains(sent[0].Data, testSocket) == false {
+		t.Errorf("Sent %v, not one command to the socket", sent)
+	}
+
+	results, err = c.EmitIRToGroup("aabbcc", "lounge")
+	if err != nil {
+		t.Fatalf("EmitIRToGroup: %v", err)
+	}
+
+	if len(results) != 2 || results[testAllOne] != nil || results[unknown] == nil { // And now the socket is skipped
+		t.Errorf("Got %v, not success for the AllOne and an error for the unknown device", results)
+	}
+
+	if sent := sentWith(transport, packet.EmitIR); len(sent) != 1 || strings.Contains(sent[0].Data, testAllOne) == false {
+		t.Errorf("Sent %v, not one code from the AllOne", sent)
+	}
+}
+
+func TestGroupList(t *testing.T) {
+	c := orvibo.NewClient()
+
+	if err := c.CreateGroup(""); err == nil {
+		t.Error("CreateGroup should have refused a group without a name")
+	}
+
+	macs := []string{testSocket}
+	c.CreateGroup("lounge", macs...)
+	c.CreateGroup("bedroom", testAllOne)
+	macs[0] = testAllOne // Changing our slice doesn't change the group
+
+	if members, found := c.GroupMembers("lounge"); found == false || len(members) != 1 || members[0] != testSocket {
+		t.Errorf("lounge has %v in it, not just %s", members, testSocket)
+	}
+
+	if groups := c.Groups(); len(groups) != 2 || groups[0] != "bedroom" || groups[1] != "lounge" {
+		t.Errorf("Got groups %v, not bedroom and lounge", groups)
+	}
+
+	c.DeleteGroup("lounge")
+	if _, found := c.GroupMembers("lounge"); found {
+		t.Error("lounge is still there after DeleteGroup")
+	}
+
+	if _, err := c.SetGroupState("lounge", true); err == nil {
+		t.Error("SetGroupState on a group that doesn't exist should have failed")
+	}
+
+	if _, err := c.EmitIRToGroup("aabbcc", "lounge"); err == nil {
+		t.Error("EmitIRToGroup on a group that doesn't exist should have failed")
+	}
+}
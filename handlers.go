@@ -0,0 +1,394 @@
+package orvibo
+
+// Every packet we get back has a command ID (e.g. 7161 for a discovery reply). Each command has its own handler,
+// registered here against its ID, so supporting a new command is a matter of writing a handler and adding it below
+
+import (
+	"errors" // For crafting our own errors
+	"net"    // For knowing who sent the message
+	"time"   // For timestamps
+
+	"github.com/Grayda/go-orvibo/packet" // For command IDs and reading packets
+)
+
+// commandHandler deals with one kind of message from a device, for the Client that received it.
+// macAdd is the MAC address of the device it came from. Return an error if the message is malformed
+// (too short to have what it should in it, say), and handleMessage raises it as a "parseerror"
+type commandHandler func(c *Client, message string, macAdd string, addr *net.UDPAddr) (bool, error)
+
+var handlers = make(map[string]commandHandler) // Our handlers, keyed by command ID
+
+// registerHandler sets the handler for a command ID, replacing any that was there before
+func registerHandler(commandID string, handler commandHandler) {
+	handlers[commandID] = handler
+}
+
+func init() {
+	registerHandler(packet.Discover, (*Client).handleDiscovery)
+	registerHandler(packet.DiscoverMAC, (*Client).handleDiscovery) // Replies to DiscoverDevice look the same as any other discovery reply
+	registerHandler(packet.Subscribe, (*Client).handleSubscription)
+	registerHandler(packet.StateControl, (*Client).handleStateControl)
+	registerHandler(packet.ReadTable, (*Client).handleTable)
+	registerHandler(packet.StateChanged, (*Client).handleStateChanged)
+	registerHandler(packet.ButtonPress, (*Client).handleButtonPress)
+	registerHandler(packet.Learn, (*Client).handleLearnedIR)
+	registerHandler(packet.RFLearn, (*Client).handleLearnedRF)
+}
+
+// handleDiscovery deals with a response to our discovery broadcast
+func (c *Client) handleDiscovery(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	model, _ := packet.Model(message) // What model it is (e.g. SOC002 for a socket, IRD014 for an AllOne)
+	deviceType := modelType(model)
+
+	if deviceType == UNKNOWN { // Follows the Orvibo "protocol", but we don't know what it is
+		c.passMessage("unknownhardwarefound", &Device{DeviceType: UNKNOWN, IP: addr, MACAddress: macAdd, Model: model, LastMessage: message})
+		return true, nil
+	}
+
+	c.devicesLock.Lock()
+	device, exists := c.Devices[macAdd] // Check to see if we've already got macAdd in our array
+
+	if exists == false { // We haven't got it in our Devices array?
+		c.deviceCount++ // Add one to the deviceCount
+		device = &Device{
+			ID:            c.deviceCount,
+			Name:          "", // No name yet
+			DeviceType:    deviceType,
+			Model:         model,
+			IP:            addr,
+			MACAddress:    macAdd,
+			Subscribed:    false,
+			Queried:       false,
+			State:         false,
+			RFSwitches:    make(map[string]RFSwitch), // Lightswitches
+			Curtains:      make(map[string]Curtain),  // Curtain motors and roller blinds
+			LastIRMessage: "",                        // The last IR message we've received
+			LastMessage:   message,                   // The last message we received
+			LastSeen:      time.Now(),                // When we last heard from it
+		}
+
+		if deviceType == SOCKET { // Sockets tell us their state in the last bit of the message. 0 or 1 for off or on
+			lastBit := message[(len(message) - 1):]
+			if lastBit == "0" {
+				device.State = false
+			} else {
+				device.State = true
+			}
+
+			device.confirmedState = device.State
+		}
+
+		c.Devices[macAdd] = device
+	} else {
+		device.LastMessage = message // Set our LastMessage
+	}
+	c.devicesLock.Unlock()
+
+	if exists == false {
+		c.addDevice(device)
+	} else {
+		c.updateAddress(device, addr)
+		snapshot := c.snapshot(device)
+		c.passEvent("existing"+foundPrefix(deviceType)+"found", snapshot, DeviceFoundEvent{Device: snapshot, Existing: true})
+	}
+
+	c.checkForReboot(device, message)
+
+	return true, nil
+}
+
+// addDevice finishes off a device we've just added to Devices: it lets the calling code know, and subscribes if AutoSubscribe is on
+func (c *Client) addDevice(device *Device) {
+	c.devicesLock.Lock()
+	associateInterface(device)
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	getLogger().Info("Found %s (%s) at %s", snapshot.MACAddress, snapshot.Model, snapshot.IP)
+	c.passEvent(foundPrefix(snapshot.DeviceType)+"found", snapshot, DeviceFoundEvent{Device: snapshot}) // Let our calling code know
+	c.streamDevice(snapshot)
+	if c.AutoSubscribe {
+		c.subscribeDevice(device)
+	}
+}
+
+// updateAddress moves device to addr if it's turned up somewhere new (after a new DHCP lease, say), and raises
+// "deviceipchanged". Only call it for replies that come from the device itself. Other controllers send commands
+// with the device's MAC address in them too, and we don't want to start sending to the WiWo app instead
+func (c *Client) updateAddress(device *Device, addr *net.UDPAddr) {
+	c.devicesLock.Lock()
+	if addr == nil || (device.IP != nil && device.IP.IP.Equal(addr.IP)) {
+		c.devicesLock.Unlock()
+		return
+	}
+
+	oldIP := ""
+	if device.IP != nil {
+		oldIP = device.IP.IP.String()
+	}
+
+	device.IP = addr
+	associateInterface(device) // It might be on a different interface now, too
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	getLogger().Info("%s has moved from %s to %s", snapshot.MACAddress, oldIP, addr.IP)
+	c.passEvent("deviceipchanged", snapshot, DeviceIPChangedEvent{Device: snapshot, OldIP: oldIP, NewIP: addr.IP.String()})
+}
+
+// foundPrefix is the start of the found events for a type of device, so we raise socketfound, allonefound etc.
+func foundPrefix(deviceType int) string {
+	if deviceType == ALLONE {
+		return "allone"
+	}
+
+	return "socket"
+}
+
+// handleSubscription deals with confirmation of a subscription
+func (c *Client) handleSubscription(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	// Sometimes we receive messages for sockets we don't know about. The WiWo
+	// app does this sometimes, as it sends messages to all AllOnes it knows about,
+	// regardless of whether or not they're active on the network. So we
+	// check to see if the socket that needs updating exists in our list. If it doesn't,
+	// we return false.
+	c.devicesLock.Lock()
+	device, found := c.Devices[macAdd]
+	if found == false {
+		c.devicesLock.Unlock()
+		return false, nil
+	}
+
+	lastBit := message[(len(message) - 1):] // Get the last bit from our message. 0 or 1 for off or on
+	if lastBit == "1" {
+		device.State = true
+	} else {
+		device.State = false
+	}
+
+	reconcile := device.rebooted && device.State != device.confirmedState // Did the state change while it was rebooting?
+	oldState := device.confirmedState
+	device.confirmedState = device.State
+	device.Subscribed = true
+	device.LastSubscribed = time.Now()
+	device.LastMessage = message // Set our LastMessage
+
+	renewed := device.renewing // It was AutoResubscribe that asked
+	device.renewing = false
+
+	rebooted := device.rebooted // We've resubscribed after a reboot, so we need to find out where it's at
+	device.rebooted = false
+
+	query := rebooted || (c.AutoQuery && device.Queried == false)
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("subscribed", snapshot)
+
+	if renewed {
+		c.passMessage("subscriptionrenewed", snapshot)
+	}
+
+	if reconcile {
+		c.passEvent("statechanged", snapshot, StateChangedEvent{Device: snapshot, OldState: oldState, NewState: snapshot.State})
+	}
+
+	if query {
+		c.queryDevice(device)
+	}
+
+	return true, nil
+}
+
+// handleStateControl deals with a state change command. For a socket, it's another controller (e.g. the WiWo app) telling it what to do
+func (c *Client) handleStateControl(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	device, found := c.device(macAdd)
+	if found == false {
+		return false, nil
+	}
+
+	if device.DeviceType == SOCKET {
+		c.devicesLock.Lock()
+		device.LastMessage = message // Set our LastMessage
+		c.devicesLock.Unlock()
+
+		c.handleSocketCommand(device, message, addr)
+		return true, nil
+	}
+
+	// Otherwise it's an AllOne, and someone's pressed an RF switch.
+	if len(message) < 50 { // Too short to have a switch state in it
+		return false, errors.New("RF switch message too short")
+	}
+
+	state := message[48:50] != "00"
+
+	c.devicesLock.Lock()
+	// If the switch has been paired, its code tells us which one it is. Otherwise, we go by its ID
+	switchID := message[36:42]
+	for id, rfSwitch := range device.RFSwitches {
+		if rfSwitch.Code != "" && rfSwitch.Code == message[50:] {
+			switchID = id
+			break
+		}
+	}
+
+	rfSwitch := device.RFSwitches[switchID]
+	rfSwitch.State = state
+	device.RFSwitches[switchID] = rfSwitch
+	device.LastMessage = message // Set our LastMessage
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passEvent("rfswitch", snapshot, RFSwitchEvent{Device: snapshot, SwitchID: switchID, State: state})
+
+	return true, nil
+}
+
+// handleTable deals with the data back after we've queried a device
+func (c *Client) handleTable(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	device, found := c.device(macAdd)
+	if found == false {
+		return false, nil
+	}
+
+	if table, _ := packet.Table(message); table == 3 { // Timers, not the device's details
+		return c.handleTimerTable(message, device)
+	}
+
+	c.devicesLock.Lock()
+	// If no name has been set, we get 16 bytes of spaces or F back, so
+	// we create a generic name so our socket name won't be blank
+	if name, named := packet.Name(message); named {
+		device.Name = name
+	} else if device.DeviceType == SOCKET {
+		device.Name = "Socket " + macAdd
+	} else {
+		device.Name = "AllOne " + macAdd
+	}
+
+	parseCountdown(device, message)
+	storeTableFour(device, message)
+
+	firstQuery := device.Queried == false
+	device.Queried = true
+	device.LastMessage = message // Set our LastMessage
+
+	ready := firstQuery && device.Subscribed // We now know both its name and its state
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("queried", snapshot)
+
+	if ready {
+		c.passMessage("deviceready", snapshot)
+	}
+
+	return true, nil
+}
+
+// handleStateChanged deals with confirmation of a state change
+func (c *Client) handleStateChanged(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	device, found := c.device(macAdd)
+	if found == false {
+		return false, nil
+	}
+
+	if len(message) < 46 { // The state is in the last byte, after the MAC address, padding and 4 more bytes
+		return false, errors.New("State change message has no state in it")
+	}
+
+	c.updateAddress(device, addr)
+
+	c.devicesLock.Lock()
+	lastBit := message[(len(message) - 1):] // Get the last bit from our message. 0 or 1 for off or on
+	if lastBit == "0" {
+		device.State = false
+	} else {
+		device.State = true
+	}
+
+	device.LastMessage = message // Set our LastMessage
+
+	// Sockets often send the same confirmation several times. Only pass it on if the state
+	// is actually different to the last one the socket confirmed (unless we've asked for everything)
+	if device.State == device.confirmedState && c.RawStateEvents == false {
+		c.devicesLock.Unlock()
+		return true, nil
+	}
+
+	oldState := device.confirmedState
+	device.confirmedState = device.State
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passEvent("statechanged", snapshot, StateChangedEvent{Device: snapshot, OldState: oldState, NewState: snapshot.State})
+
+	return true, nil
+}
+
+// handleButtonPress deals with someone pressing the button on the top of an AllOne
+func (c *Client) handleButtonPress(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	device, found := c.device(macAdd)
+	if found == false {
+		return false, nil
+	}
+
+	c.devicesLock.Lock()
+	device.LastMessage = message // Set our LastMessage
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("buttonpress", snapshot)
+
+	return true, nil
+}
+
+// handleLearnedIR deals with an IR code coming back after learning mode
+func (c *Client) handleLearnedIR(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	device, found := c.device(macAdd)
+	if found == false {
+		return false, nil
+	}
+
+	// 686400186c73accf232a5ffa202020202020000000000000 is the reply to going into learning mode, with no code in it.
+	// A learned code comes after two bytes of length, so anything between the two has lost its code
+	if len(message) > 48 && len(message) <= 52 {
+		return false, errors.New("Learned IR message has no code in it")
+	}
+
+	if len(message) > 52 {
+		c.devicesLock.Lock()
+		device.LastIRMessage = message[52:]
+		device.irCodesLearned++
+		device.LastMessage = message // Set our LastMessage
+		snapshot := device.copy()
+		c.devicesLock.Unlock()
+
+		c.passEvent("ircode", snapshot, IRLearnedEvent{Device: snapshot, Code: snapshot.LastIRMessage})
+	}
+
+	return true, nil
+}
+
+// handleLearnedRF deals with an RF code coming back after RF learning mode. We're guessing it's laid out the
+// same as a learned IR code, but nobody's captured one yet (see ExperimentalRF). The short reply we get when the AllOne enters learning mode has no code in it
+func (c *Client) handleLearnedRF(message string, macAdd string, addr *net.UDPAddr) (bool, error) {
+	device, found := c.device(macAdd)
+	if found == false {
+		return false, nil
+	}
+
+	if len(message) > 52 {
+		c.devicesLock.Lock()
+		device.lastRFCode = message[52:]
+		device.rfCodesLearned++
+		device.LastMessage = message // Set our LastMessage
+		snapshot := device.copy()
+		c.devicesLock.Unlock()
+
+		c.passEvent("rfcode", snapshot, RFLearnedEvent{Device: snapshot, Code: message[52:]})
+	}
+
+	return true, nil
+}
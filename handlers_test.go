@@ -0,0 +1,89 @@
+package orvibo
+
+import (
+	"net" // For addresses
+	"testing"
+
+	"github.com/Grayda/go-orvibo/packet" // For building packets
+)
+
+// TestHandleMessageGarbage feeds handleMessage things that aren't proper packets. None of them should panic,
+// and each should raise a parseerror and return an error
+func TestHandleMessageGarbage(t *testing.T) {
+	const allOne = "accf00000002"
+	addr := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 51), Port: 10000}
+
+	c := NewClient()
+	c.Devices[allOne] = &Device{MACAddress: allOne, DeviceType: ALLONE, IP: addr}
+
+	noCode, _ := packet.NewPacket(packet.Learn, allOne, "000000000000"+"0000") // A length, but no code after it
+	halfLength, _ := packet.NewPacket(packet.Learn, allOne, "000000000000"+"00")
+	shortState, _ := packet.NewPacket(packet.StateChanged, allOne, "") // Should have the state after the MAC address
+
+	for name, message := range map[string]string{
+		"blank":                  "",
+		"too short":              "6864",
+		"odd length":             packet.DiscoverAll + "0",
+		"not hex":                "6864000671zz",
+		"wrong magic word":       "686500067161",
+		"too long for header":    packet.DiscoverAll + "0000",
+		"cut off":                noCode[:len(noCode)-4],
+		"learned, no code":       noCode,
+		"learned, half a length": halfLength,
+		"state, no state":        shortState,
+	} {
+		drainEvents(c)
+
+		if _, err := c.handleMessage(message, addr); err == nil {
+			t.Errorf("%s: handleMessage(%q) didn't return an error", name, message)
+			continue
+		}
+
+		events := drainEvents(c)
+		if len(events) == 0 || events[len(events)-1].Name != "parseerror" {
+			t.Errorf("%s: got %+v, not a parseerror", name, events)
+			continue
+		}
+
+		if payload := events[len(events)-1].Payload.(ParseErrorEvent); payload.Message != message || payload.From != addr.String() || payload.Reason == "" {
+			t.Errorf("%s: parseerror has %+v", name, payload)
+		}
+	}
+
+	// The reply to going into learning mode has no code either, but that's expected
+	learning, _ := packet.NewPacket(packet.Learn, allOne, "000000000000")
+	if _, err := c.handleMessage(learning, addr); err != nil {
+		t.Errorf("The learning mode reply returned %v", err)
+	}
+
+	for _, event := range drainEvents(c) {
+		if event.Name == "ircode" || event.Name == "parseerror" {
+			t.Errorf("The learning mode reply raised %s", event.Name)
+		}
+	}
+}
+
+// FuzzHandleMessage feeds handleMessage garbage from a known AllOne. Whatever it is, handleMessage shouldn't panic
+// (it recovers, but raises a parseerror saying it panicked, which we check for)
+func FuzzHandleMessage(f *testing.F) {
+	const allOne = "accf00000002"
+	for _, commandID := range []string{packet.Discover, packet.Subscribe, packet.StateChanged, packet.ReadTable, packet.Learn, packet.RFLearn, packet.ButtonPress} {
+		for _, payload := range []string{"", "00", "0000000000", "000000000000" + "0600" + "aabbccddeeff"} {
+			msg, _ := packet.NewPacket(commandID, allOne, payload)
+			f.Add(msg)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, message string) {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 51), Port: 10000}
+		c := NewClient()
+		c.Devices[allOne] = &Device{MACAddress: allOne, DeviceType: ALLONE, IP: addr}
+
+		c.handleMessage(message, addr)
+		for _, event := range drainEvents(c) {
+			if event.Name == "parseerror" && len(event.Payload.(ParseErrorEvent).Reason) > 8 && event.Payload.(ParseErrorEvent).Reason[:8] == "Panicked" {
+				t.Errorf("handleMessage(%q) panicked: %s", message, event.Payload.(ParseErrorEvent).Reason)
+			}
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package orvibo
+
+// Health monitoring. A socket that's been unplugged doesn't tell us it's going, so without this we'd keep on
+// sending it commands forever. The health monitor pings devices we haven't heard from in a while, and raises
+// "deviceoffline" when one has been quiet for OfflineAfter. Hearing anything from it again raises "deviceonline"
+
+import (
+	"errors" // For crafting our own errors
+	"time"   // For our interval
+)
+
+// StartHealthMonitor checks on every device every interval, until StopHealthMonitor or Close is called.
+// Devices we've subscribed to that we haven't heard from since the last check are sent a subscription, which
+// they answer if they're still there. Calling it while it's already running just changes the interval
+func (c *Client) StartHealthMonitor(interval time.Duration) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return errors.New("Health monitor interval must be more than 0")
+	}
+
+	c.StopHealthMonitor()
+
+	c.healthMonitorLock.Lock()
+	defer c.healthMonitorLock.Unlock()
+
+	stop := make(chan bool)
+	c.stopHealthMonitor = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.checkHealth(interval)
+			case <-stop:
+				return
+			case <-c.closing:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopHealthMonitor stops the health monitor started by StartHealthMonitor. It's safe to call
+// even if it isn't running
+func (c *Client) StopHealthMonitor() {
+	c.healthMonitorLock.Lock()
+	defer c.healthMonitorLock.Unlock()
+
+	if c.stopHealthMonitor != nil {
+		close(c.stopHealthMonitor)
+		c.stopHealthMonitor = nil
+	}
+}
+
+// checkHealth raises "deviceoffline" for any device we haven't heard from in OfflineAfter, and pings the
+// subscribed devices we haven't heard from in interval
+func (c *Client) checkHealth(interval time.Duration) {
+	for _, device := range c.deviceList() {
+		c.devicesLock.Lock()
+		since := time.Since(device.LastSeen)
+		if since < interval { // Heard from it recently, so it's fine
+			c.devicesLock.Unlock()
+			continue
+		}
+
+		offline := since >= OfflineAfter && device.Offline == false
+		if offline {
+			device.Offline = true
+		}
+
+		subscribed := device.LastSubscribed.IsZero() == false
+		snapshot := device.copy()
+		c.devicesLock.Unlock()
+
+		if offline {
+			getLogger().Warn("Haven't heard from %s in %s. It's offline", device.MACAddress, since.Round(time.Second))
+			c.passMessage("deviceoffline", snapshot)
+		}
+
+		if subscribed { // Only devices we've subscribed to answer a subscription
+			c.subscribeDevice(device)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package orvibo
+
+// Per-interface device association. On machines with more than one network interface, we remember which
+// interface each device was found on, so we can send its commands back out the same way
+
+import (
+	"fmt"     // For building our error messages
+	"net"     // For looking at our interfaces
+	"strconv" // For port numbers
+)
+
+// interfaceFor finds the local interface whose subnet contains ip, or nil if none of them do
+func interfaceFor(ip net.IP) *net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.Contains(ip) {
+				return &ifaces[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// associateInterface records which interface a device was found on. Hold devicesLock while calling it
+func associateInterface(device *Device) {
+	if device.IP == nil {
+		return
+	}
+
+	iface := interfaceFor(device.IP.IP)
+	if iface == nil { // Not on any of our subnets (e.g. routed from elsewhere), so we leave it up to the OS
+		device.Interface = ""
+		device.ifIndex = 0
+		return
+	}
+
+	device.Interface = iface.Name
+	device.ifIndex = iface.Index
+}
+
+// interfaceIPv4 returns the first IPv4 address of the named interface
+func interfaceIPv4(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("Interface %s hasn't got an IPv4 address", name)
+}
+
+// bindAddr is the address Prepare listens on: BindAddr if it's set, otherwise Port on Interface's
+// address, otherwise Port on every address
+func (c *Client) bindAddr() (string, error) {
+	if c.BindAddr != "" {
+		return c.BindAddr, nil
+	}
+
+	if c.Interface != "" {
+		ip, err := interfaceIPv4(c.Interface)
+		if err != nil {
+			return "", err
+		}
+
+		return net.JoinHostPort(ip.String(), strconv.Itoa(c.listenPort())), nil
+	}
+
+	return ":" + strconv.Itoa(c.listenPort()), nil
+}
+
+// localIP is our own IP address: the one in BindAddr or on Interface if they're set,
+// otherwise the first one getLocalIP finds
+func (c *Client) localIP() (string, error) {
+	if c.BindAddr != "" {
+		if host, _, err := net.SplitHostPort(c.BindAddr); err == nil && net.ParseIP(host) != nil && net.ParseIP(host).IsUnspecified() == false {
+			return host, nil
+		}
+	}
+
+	if c.Interface != "" {
+		ip, err := interfaceIPv4(c.Interface)
+		if err != nil {
+			return "", err
+		}
+
+		return ip.String(), nil
+	}
+
+	return getLocalIP()
+}
+
+// listenPort is the port we listen on: Port, or devicePort if it isn't set
+func (c *Client) listenPort() int {
+	if c.Port <= 0 {
+		return devicePort
+	}
+
+	return c.Port
+}
@@ -0,0 +1,180 @@
+package orvibo
+
+// IR code conversion. Public IR databases (LIRC, irdb, Global Caché and so on) mostly use Pronto HEX or raw
+// mark / space timings, so these convert between those and the codes the AllOne learns and emits.
+//
+// UNVERIFIED: we don't have a capture that confirms how the AllOne lays out its IR codes. These conversions
+// assume a code is a run of 16 bit little endian durations in microseconds, alternating mark (IR on) and space
+// (IR off), starting with a mark, which is how most IR blasters store raw codes. That assumption hasn't been
+// checked against a real AllOne, so a converted code may not play back properly. Until it has, everything that
+// reads or writes an AllOne code here returns ErrExperimentalIR unless ExperimentalIR is set. Learned codes (from
+// LearnIR) don't go through any of this, and are emitted exactly as they were heard. If you can confirm (or
+// disprove) the layout with a capture of a learned code and the remote's known timings, please send it in.
+//
+// We also assume the AllOne doesn't record the carrier frequency, and uses its own (38kHz or thereabouts,
+// which suits nearly every remote)
+
+import (
+	"encoding/hex" // For reading and writing codes
+	"errors"       // For crafting our own errors
+	"fmt"          // For building our error messages
+	"math"         // For rounding
+	"strconv"      // For reading Pronto words
+	"strings"      // For splitting up Pronto codes
+)
+
+// ExperimentalIR lets the conversions here (and the NEC and RC5 encoders built on them) run. The AllOne's IR code
+// layout is unverified, so the codes they build might not work. Shared by every Client
+var ExperimentalIR = false
+
+// ErrExperimentalIR is returned by the IR conversions and encoders when ExperimentalIR isn't set
+var ErrExperimentalIR = errors.New("Converting IR codes is experimental. Set ExperimentalIR to try it")
+
+// prontoUnit is how long one tick of the Pronto clock is, in microseconds. The carrier frequency word in a
+// Pronto code is the carrier's period in these ticks
+const prontoUnit = 0.241246
+
+// defaultCarrier is the carrier frequency (in Hz) we put in Pronto codes, since the AllOne doesn't tell us
+const defaultCarrier = 38000
+
+// prontoTrailingGap is the space (in microseconds) we add to the end of a code with an odd number of timings,
+// since Pronto codes have to be made of mark / space pairs
+const prontoTrailingGap = 40000
+
+// RawTimingsToOrvibo turns raw timings (in microseconds, mark first, then space, mark and so on) into an
+// AllOne IR code. Negative numbers are treated as spaces, so LIRC-style "+9000 -4500" timings work too.
+// The AllOne's code layout is unverified (see the top of this file), so this returns ErrExperimentalIR unless
+// ExperimentalIR is set
+func RawTimingsToOrvibo(timings []int) (string, error) {
+	if ExperimentalIR == false {
+		return "", ErrExperimentalIR
+	}
+
+	if len(timings) == 0 {
+		return "", errors.New("There are no timings to convert")
+	}
+
+	code := make([]byte, 0, len(timings)*2)
+	for _, timing := range timings {
+		if timing < 0 {
+			timing = -timing
+		}
+
+		if timing == 0 || timing > math.MaxUint16 {
+			return "", fmt.Errorf("%dus can't go in an AllOne IR code. Timings have to be between 1 and %dus", timing, math.MaxUint16)
+		}
+
+		code = append(code, byte(timing), byte(timing>>8))
+	}
+
+	return hex.EncodeToString(code), nil
+}
+
+// OrviboToRawTimings turns an AllOne IR code into raw timings in microseconds, mark first. The AllOne's code
+// layout is unverified (see the top of this file), so this returns ErrExperimentalIR unless ExperimentalIR is set
+func OrviboToRawTimings(code string) ([]int, error) {
+	if ExperimentalIR == false {
+		return nil, ErrExperimentalIR
+	}
+
+	b, err := hex.DecodeString(code)
+	if err != nil || len(b) == 0 || len(b)%2 != 0 {
+		return nil, fmt.Errorf("%q isn't an AllOne IR code", code)
+	}
+
+	timings := make([]int, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		timings = append(timings, int(b[i])|int(b[i+1])<<8)
+	}
+
+	return timings, nil
+}
+
+// ProntoToOrvibo turns a Pronto HEX code (e.g. "0000 006D 0022 0002 0157 00AC ...") into an AllOne IR code.
+// Only learned (raw, 0000) Pronto codes are supported. If the code has both a once and a repeat sequence,
+// we use the once sequence, since that's what a single button press sends. The AllOne's code layout is
+// unverified (see the top of this file), so this returns ErrExperimentalIR unless ExperimentalIR is set
+func ProntoToOrvibo(pronto string) (string, error) {
+	timings, err := prontoToTimings(pronto)
+	if err != nil {
+		return "", err
+	}
+
+	return RawTimingsToOrvibo(timings)
+}
+
+// prontoToTimings reads a learned Pronto HEX code into raw timings in microseconds, mark first
+func prontoToTimings(pronto string) ([]int, error) {
+	var words []int
+	for _, field := range strings.Fields(pronto) {
+		word, err := strconv.ParseUint(field, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a Pronto word", field)
+		}
+
+		words = append(words, int(word))
+	}
+
+	if len(words) < 4 {
+		return nil, errors.New("Pronto codes need at least 4 words")
+	}
+
+	if words[0] != 0 {
+		return nil, fmt.Errorf("Only learned (0000) Pronto codes are supported, not %04X", words[0])
+	}
+
+	if words[1] == 0 {
+		return nil, errors.New("Pronto code has no carrier frequency")
+	}
+
+	once, repeat := words[2], words[3]
+	if len(words) != 4+(once+repeat)*2 {
+		return nil, fmt.Errorf("Pronto code should have %d words, but it has %d", 4+(once+repeat)*2, len(words))
+	}
+
+	sequence := words[4 : 4+once*2]
+	if once == 0 { // No once sequence, so the repeat sequence is all there is
+		sequence = words[4:]
+	}
+
+	period := float64(words[1]) * prontoUnit // How long one carrier cycle is, in microseconds
+	timings := make([]int, len(sequence))
+	for i, cycles := range sequence {
+		timings[i] = int(math.Round(float64(cycles) * period))
+	}
+
+	return timings, nil
+}
+
+// OrviboToPronto turns an AllOne IR code into a Pronto HEX code, with a 38kHz carrier. The AllOne's code
+// layout is unverified (see the top of this file), so this returns ErrExperimentalIR unless ExperimentalIR is set
+func OrviboToPronto(code string) (string, error) {
+	timings, err := OrviboToRawTimings(code)
+	if err != nil {
+		return "", err
+	}
+
+	return timingsToPronto(timings), nil
+}
+
+// timingsToPronto writes raw timings in microseconds, mark first, as a learned Pronto HEX code with a 38kHz carrier
+func timingsToPronto(timings []int) string {
+	if len(timings)%2 != 0 { // Pronto needs mark / space pairs
+		timings = append(timings, prontoTrailingGap)
+	}
+
+	carrierWord := int(math.Round(1000000 / (defaultCarrier * prontoUnit)))
+	period := float64(carrierWord) * prontoUnit
+
+	words := []string{"0000", fmt.Sprintf("%04X", carrierWord), fmt.Sprintf("%04X", len(timings)/2), "0000"}
+	for _, timing := range timings {
+		cycles := int(math.Round(float64(timing) / period))
+		if cycles > math.MaxUint16 {
+			cycles = math.MaxUint16
+		}
+
+		words = append(words, fmt.Sprintf("%04X", cycles))
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,100 @@
+package orvibo
+
+// These only check the parts we can check against a published format: Pronto HEX, and raw timings. How an AllOne
+// lays out its codes is still a guess (see ir.go), so nothing here says a converted code will work on one
+
+import (
+	"testing"
+)
+
+// withExperimentalIR sets ExperimentalIR for one test, putting it back afterwards
+func withExperimentalIR(t *testing.T) {
+	old := ExperimentalIR
+	ExperimentalIR = true
+	t.Cleanup(func() { ExperimentalIR = old })
+}
+
+func TestExperimentalIR(t *testing.T) {
+	if _, err := RawTimingsToOrvibo([]int{9000, 4500}); err != ErrExperimentalIR {
+		t.Errorf("RawTimingsToOrvibo returned %v, not ErrExperimentalIR", err)
+	}
+
+	if _, err := OrviboToRawTimings("2823"); err != ErrExperimentalIR {
+		t.Errorf("OrviboToRawTimings returned %v, not ErrExperimentalIR", err)
+	}
+
+	if _, err := ProntoToOrvibo("0000 006D 0001 0000 0157 00AC"); err != ErrExperimentalIR {
+		t.Errorf("ProntoToOrvibo returned %v, not ErrExperimentalIR", err)
+	}
+
+	if _, err := OrviboToPronto("2823"); err != ErrExperimentalIR {
+		t.Errorf("OrviboToPronto returned %v, not ErrExperimentalIR", err)
+	}
+}
+
+func TestProntoToTimings(t *testing.T) {
+	// The NEC leader as it appears in published Pronto codes: a 38kHz carrier (006D), then 343 and 172 cycles,
+	// which NEC says are 9ms and 4.5ms
+	timings, err := prontoToTimings("0000 006D 0001 0000 0157 00AC")
+	if err != nil {
+		t.Fatalf("prontoToTimings: %v", err)
+	}
+
+	if len(timings) != 2 || within(timings[0], 9000, 90) == false || within(timings[1], 4500, 45) == false {
+		t.Errorf("Got %v, not about 9000 and 4500", timings)
+	}
+
+	// With a once and a repeat sequence, only the once sequence is used
+	if timings, _ := prontoToTimings("0000 006D 0001 0001 0157 00AC 0157 0056"); len(timings) != 2 || within(timings[1], 4500, 45) == false {
+		t.Errorf("Got %v from the once sequence", timings)
+	}
+
+	// With no once sequence, the repeat sequence is all there is
+	if timings, _ := prontoToTimings("0000 006D 0000 0001 0157 0056"); len(timings) != 2 || within(timings[1], 2250, 25) == false {
+		t.Errorf("Got %v from the repeat sequence", timings)
+	}
+
+	for _, bad := range []string{
+		"0000 006D",                     // Too short
+		"0100 006D 0001 0000 0156 00AB", // Not a learned code
+		"0000 0000 0001 0000 0156 00AB", // No carrier
+		"0000 006D 0002 0000 0156 00AB", // Missing a pair
+		"0000 006D 0001 0000 0156 zz",   // Not hex
+	} {
+		if _, err := prontoToTimings(bad); err == nil {
+			t.Errorf("prontoToTimings(%q) should have failed", bad)
+		}
+	}
+}
+
+func TestTimingsToPronto(t *testing.T) {
+	if pronto := timingsToPronto([]int{9000, 4500}); pronto != "0000 006D 0001 0000 0156 00AB" {
+		t.Errorf("Got %s for the NEC leader", pronto)
+	}
+
+	// An odd number of timings gets a 40ms space on the end to make up the pair
+	if pronto := timingsToPronto([]int{560}); pronto != "0000 006D 0001 0000 0015 05F1" {
+		t.Errorf("Got %s", pronto)
+	}
+}
+
+func TestRawTimingsToOrviboRejects(t *testing.T) {
+	withExperimentalIR(t)
+
+	for _, bad := range [][]int{nil, {0}, {70000}} {
+		if _, err := RawTimingsToOrvibo(bad); err == nil {
+			t.Errorf("RawTimingsToOrvibo(%v) should have failed", bad)
+		}
+	}
+
+	for _, bad := range []string{"", "abc", "zz00"} {
+		if _, err := OrviboToRawTimings(bad); err == nil {
+			t.Errorf("OrviboToRawTimings(%q) should have failed", bad)
+		}
+	}
+}
+
+// within checks if got is no more than slack away from want
+func within(got int, want int, slack int) bool {
+	return got >= want-slack && got <= want+slack
+}
@@ -0,0 +1,148 @@
+package orvibo
+
+// The IR code library. Learned IR codes are long hex strings that nobody wants to copy and paste around, so
+// we keep them here under friendly names (e.g. "TV Power"), and save them to disk so they survive restarts.
+// Like the audit log, there's one library, shared by every Client
+
+import (
+	"encoding/hex"  // For checking IR codes
+	"encoding/json" // For reading and writing the library
+	"errors"        // For crafting our own errors
+	"fmt"           // For building our error messages
+	"io/ioutil"     // For reading the library file
+	"os"            // For checking whether the library file exists
+	"sort"          // For listing codes in order
+	"sync"          // Codes can be saved from more than one goroutine
+)
+
+// IRCodeFile, if set, is the JSON file the IR code library is saved to every time it changes.
+// LoadIRCodes sets it for you
+var IRCodeFile = ""
+
+var irCodes = make(map[string]IRCode) // Our IR code library, keyed by name
+var irCodesLock sync.Mutex            // Codes can be saved from more than one goroutine
+
+// LoadIRCodes reads the IR code library from a JSON file (replacing whatever's in it now), and sets IRCodeFile
+// so any changes are saved back to it. A file that doesn't exist yet is an empty library, not an error
+func LoadIRCodes(path string) error {
+	irCodesLock.Lock()
+	defer irCodesLock.Unlock()
+
+	loaded := make(map[string]IRCode)
+	data, err := ioutil.ReadFile(path)
+	if err != nil && os.IsNotExist(err) == false {
+		return err
+	}
+
+	if err == nil {
+		var codes []IRCode
+		if err = json.Unmarshal(data, &codes); err != nil {
+			return fmt.Errorf("Couldn't read IR codes from %s: %v", path, err)
+		}
+
+		for _, code := range codes {
+			loaded[code.Name] = code
+		}
+	}
+
+	irCodes = loaded
+	IRCodeFile = path
+	return nil
+}
+
+// SaveIRCode adds an IR code (as a hex string, like the ones from "ircode" events) to the library under name,
+// replacing any code that already has that name. If IRCodeFile is set, the library is saved to it
+func SaveIRCode(name string, code string) (IRCode, error) {
+	if name == "" {
+		return IRCode{}, errors.New("IR codes need a name")
+	}
+
+	if _, err := hex.DecodeString(code); err != nil || code == "" {
+		return IRCode{}, fmt.Errorf("%q isn't an IR code. It should be a hex string", code)
+	}
+
+	irCodesLock.Lock()
+	defer irCodesLock.Unlock()
+
+	saved, exists := irCodes[name]
+	if exists == false { // A new code gets the next ID
+		for _, other := range irCodes {
+			if other.ID > saved.ID {
+				saved.ID = other.ID
+			}
+		}
+		saved.ID++
+	}
+
+	saved.Name = name
+	saved.Code = code
+	irCodes[name] = saved
+
+	return saved, writeIRCodes()
+}
+
+// GetIRCode looks up an IR code in the library by name
+func GetIRCode(name string) (IRCode, bool) {
+	irCodesLock.Lock()
+	defer irCodesLock.Unlock()
+
+	code, found := irCodes[name]
+	return code, found
+}
+
+// DeleteIRCode removes an IR code from the library. If IRCodeFile is set, the library is saved to it
+func DeleteIRCode(name string) error {
+	irCodesLock.Lock()
+	defer irCodesLock.Unlock()
+
+	if _, found := irCodes[name]; found == false {
+		return fmt.Errorf("There's no IR code called %q", name)
+	}
+
+	delete(irCodes, name)
+	return writeIRCodes()
+}
+
+// IRCodes returns every code in the library, in the order they were added
+func IRCodes() []IRCode {
+	irCodesLock.Lock()
+	defer irCodesLock.Unlock()
+
+	return sortedIRCodes()
+}
+
+// EmitIRByName looks up an IR code in the library, and emits it from the AllOne with the MAC address macAdd
+// (or every AllOne, if macAdd is "ALL")
+func (c *Client) EmitIRByName(name string, macAdd string, opts ...CommandOption) error {
+	code, found := GetIRCode(name)
+	if found == false {
+		return fmt.Errorf("There's no IR code called %q", name)
+	}
+
+	return c.emitIR(code.Code, macAdd, getCommandOptions(opts))
+}
+
+// sortedIRCodes returns the library as a slice, in ID order. irCodesLock must be held
+func sortedIRCodes() []IRCode {
+	codes := make([]IRCode, 0, len(irCodes))
+	for _, code := range irCodes {
+		codes = append(codes, code)
+	}
+
+	sort.Slice(codes, func(i, j int) bool { return codes[i].ID < codes[j].ID })
+	return codes
+}
+
+// writeIRCodes saves the library to IRCodeFile, if it's set. irCodesLock must be held
+func writeIRCodes() error {
+	if IRCodeFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(sortedIRCodes(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(IRCodeFile, data)
+}
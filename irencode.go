@@ -0,0 +1,151 @@
+package orvibo
+
+// IR protocol encoders. Most remotes speak one of a handful of well known protocols, so rather than learning
+// every button from the remote, you can look up its address and command numbers and have us build the code.
+//
+// UNVERIFIED: the NEC and RC5 timings here are the published ones, but they're turned into an AllOne code with
+// RawTimingsToOrvibo, and the AllOne's code layout hasn't been checked against a capture (see ir.go). Until it
+// has, a code built here may not work, so everything here returns ErrExperimentalIR unless ExperimentalIR is set.
+// If a code doesn't work, learn the button with LearnIR instead
+
+import (
+	"errors" // For crafting our own errors
+	"fmt"    // For building our error messages
+)
+
+// NEC timings, in microseconds
+const (
+	necLeaderMark  = 9000
+	necLeaderSpace = 4500
+	necBitMark     = 560
+	necZeroSpace   = 560
+	necOneSpace    = 1690
+)
+
+// rc5HalfBit is how long half an RC5 bit is, in microseconds
+const rc5HalfBit = 889
+
+// EncodeNEC builds an AllOne IR code for an NEC command. Addresses up to 255 are sent as standard NEC (the address,
+// then its inverse). Bigger ones are sent as extended NEC, with the whole 16 bit address instead. The AllOne's code
+// layout is unverified (see the top of this file), so this returns ErrExperimentalIR unless ExperimentalIR is set
+func EncodeNEC(address uint16, command uint8) (string, error) {
+	if ExperimentalIR == false {
+		return "", ErrExperimentalIR
+	}
+
+	return RawTimingsToOrvibo(necTimings(address, command))
+}
+
+// necTimings lays out an NEC command as raw timings in microseconds, mark first
+func necTimings(address uint16, command uint8) []int {
+	var data [4]byte
+	if address <= 0xff {
+		data[0], data[1] = byte(address), ^byte(address)
+	} else {
+		data[0], data[1] = byte(address), byte(address>>8)
+	}
+	data[2], data[3] = command, ^command
+
+	timings := []int{necLeaderMark, necLeaderSpace}
+	for _, b := range data {
+		for bit := uint(0); bit < 8; bit++ { // Least significant bit first
+			if b&(1<<bit) != 0 {
+				timings = append(timings, necBitMark, necOneSpace)
+			} else {
+				timings = append(timings, necBitMark, necZeroSpace)
+			}
+		}
+	}
+	timings = append(timings, necBitMark) // The stop bit
+
+	return timings
+}
+
+// EncodeRC5 builds an AllOne IR code for an RC5 command. address is 0 to 31 and command is 0 to 127 (commands
+// over 63 are sent as RC5X). Remotes flip toggle every time a button is pressed, so the device can tell a
+// second press from a held button. If you're sending the same command twice in a row, flip it yourself. The
+// AllOne's code layout is unverified (see the top of this file), so this returns ErrExperimentalIR unless
+// ExperimentalIR is set
+func EncodeRC5(address uint8, command uint8, toggle bool) (string, error) {
+	if ExperimentalIR == false {
+		return "", ErrExperimentalIR
+	}
+
+	timings, err := rc5Timings(address, command, toggle)
+	if err != nil {
+		return "", err
+	}
+
+	return RawTimingsToOrvibo(timings)
+}
+
+// rc5Timings lays out an RC5 command as raw timings in microseconds, mark first
+func rc5Timings(address uint8, command uint8, toggle bool) ([]int, error) {
+	if address > 31 {
+		return nil, fmt.Errorf("RC5 addresses go up to 31, not %d", address)
+	}
+
+	if command > 127 {
+		return nil, fmt.Errorf("RC5 commands go up to 127, not %d", command)
+	}
+
+	// Start bit, field bit (the inverse of the 7th command bit), toggle, 5 address bits and 6 command bits, most significant first
+	bits := []bool{true, command < 64, toggle}
+	for bit := 4; bit >= 0; bit-- {
+		bits = append(bits, address&(1<<uint(bit)) != 0)
+	}
+	for bit := 5; bit >= 0; bit-- {
+		bits = append(bits, command&(1<<uint(bit)) != 0)
+	}
+
+	// RC5 is Manchester coded: a 1 is a space then a mark, and a 0 is a mark then a space. We build up
+	// the half bits, then join up the runs of mark and space into timings
+	var halves []bool // true for mark
+	for _, bit := range bits {
+		halves = append(halves, bit == false, bit)
+	}
+
+	var timings []int
+	mark := false
+	for _, half := range halves {
+		if len(timings) > 0 && half == mark {
+			timings[len(timings)-1] += rc5HalfBit
+			continue
+		}
+
+		if len(timings) == 0 && half == false { // The code has to start with a mark, and a leading space is just silence anyway
+			continue
+		}
+
+		timings = append(timings, rc5HalfBit)
+		mark = half
+	}
+
+	if len(timings) == 0 {
+		return nil, errors.New("RC5 code came out empty")
+	}
+
+	return timings, nil
+}
+
+// EmitIRNEC builds an NEC code with EncodeNEC and emits it from the AllOne with the MAC address macAdd (or every
+// AllOne, if macAdd is "ALL"). Experimental: returns ErrExperimentalIR unless ExperimentalIR is set
+func (c *Client) EmitIRNEC(address uint16, command uint8, macAdd string, opts ...CommandOption) error {
+	code, err := EncodeNEC(address, command)
+	if err != nil {
+		return err
+	}
+
+	return c.emitIR(code, macAdd, getCommandOptions(opts))
+}
+
+// EmitIRRC5 builds an RC5 code with EncodeRC5 and emits it from the AllOne with the MAC address macAdd (or every
+// AllOne, if macAdd is "ALL"). Experimental: returns ErrExperimentalIR unless ExperimentalIR is set
+func (c *Client) EmitIRRC5(address uint8, command uint8, toggle bool, macAdd string, opts ...CommandOption) error {
+	code, err := EncodeRC5(address, command, toggle)
+	if err != nil {
+		return err
+	}
+
+	return c.emitIR(code, macAdd, getCommandOptions(opts))
+}
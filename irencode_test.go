@@ -0,0 +1,129 @@
+package orvibo
+
+// These check the timings against the published NEC and RC5 specs. Whether an AllOne plays them back properly
+// is still a guess (see ir.go)
+
+import (
+	"reflect" // For comparing timings
+	"testing"
+)
+
+func TestEncodeNECExperimental(t *testing.T) {
+	if _, err := EncodeNEC(0x04, 0x08); err != ErrExperimentalIR {
+		t.Errorf("EncodeNEC returned %v, not ErrExperimentalIR", err)
+	}
+
+	if _, err := EncodeRC5(5, 12, false); err != ErrExperimentalIR {
+		t.Errorf("EncodeRC5 returned %v, not ErrExperimentalIR", err)
+	}
+
+	c := NewClient() // Not prepared, so if the gate wasn't checked first we'd get ErrNotPrepared
+	if err := c.EmitIRNEC(0x04, 0x08, "accf00000002"); err != ErrExperimentalIR {
+		t.Errorf("EmitIRNEC returned %v, not ErrExperimentalIR", err)
+	}
+
+	if err := c.EmitIRRC5(5, 12, false, "accf00000002"); err != ErrExperimentalIR {
+		t.Errorf("EmitIRRC5 returned %v, not ErrExperimentalIR", err)
+	}
+}
+
+func TestNECTimings(t *testing.T) {
+	timings := necTimings(0x04, 0x08)
+	if len(timings) != 2+32*2+1 { // Leader, 32 bits and a stop bit
+		t.Fatalf("Got %d timings, not 67", len(timings))
+	}
+
+	if timings[0] != 9000 || timings[1] != 4500 || timings[len(timings)-1] != 560 {
+		t.Errorf("Leader is %v and the stop bit is %d", timings[:2], timings[len(timings)-1])
+	}
+
+	// Address, inverse address, command, inverse command, least significant bit first. Every bit is a 560us
+	// mark, then a 560us space for a 0 or a 1690us space for a 1
+	want := uint32(0x04) | uint32(0xfb)<<8 | uint32(0x08)<<16 | uint32(0xf7)<<24
+	var got uint32
+	for bit := 0; bit < 32; bit++ {
+		if timings[2+bit*2] != 560 {
+			t.Errorf("Bit %d has a %dus mark", bit, timings[2+bit*2])
+		}
+
+		if timings[3+bit*2] == 1690 {
+			got |= 1 << uint(bit)
+		}
+	}
+
+	if got != want {
+		t.Errorf("Sent %08x, not %08x", got, want)
+	}
+
+	// Extended NEC sends the whole 16 bit address instead of the address and its inverse
+	extended := necTimings(0x1234, 0x08)
+	var address uint32
+	for bit := 0; bit < 16; bit++ {
+		if extended[3+bit*2] == 1690 {
+			address |= 1 << uint(bit)
+		}
+	}
+
+	if address != 0x1234 {
+		t.Errorf("Sent address %04x, not 1234", address)
+	}
+}
+
+func TestRC5Timings(t *testing.T) {
+	timings, err := rc5Timings(5, 12, false)
+	if err != nil {
+		t.Fatalf("rc5Timings: %v", err)
+	}
+
+	// Turn the timings back into half bits (a leading space is dropped, since it's just silence), then read the
+	// Manchester code: a 1 is a space then a mark, a 0 is a mark then a space
+	halves := []bool{false}
+	mark := true
+	for _, timing := range timings {
+		if timing != rc5HalfBit && timing != 2*rc5HalfBit {
+			t.Fatalf("%dus isn't a half or whole RC5 bit", timing)
+		}
+
+		for i := 0; i < timing/rc5HalfBit; i++ {
+			halves = append(halves, mark)
+		}
+		mark = mark == false
+	}
+
+	if len(halves)%2 != 0 { // A trailing space isn't sent either
+		halves = append(halves, false)
+	}
+
+	var bits []bool
+	for i := 0; i+1 < len(halves); i += 2 {
+		if halves[i] == halves[i+1] {
+			t.Fatalf("Half bits %d and %d are the same, so this isn't Manchester code", i, i+1)
+		}
+
+		bits = append(bits, halves[i+1])
+	}
+
+	// Start, field (set for commands under 64), toggle, address 00101, command 001100
+	want := []bool{true, true, false, false, false, true, false, true, false, false, true, true, false, false}
+	if len(bits) != len(want) {
+		t.Fatalf("Got %d bits, not %d", len(bits), len(want))
+	}
+
+	for i := range want {
+		if bits[i] != want[i] {
+			t.Errorf("Bit %d is %v, not %v", i, bits[i], want[i])
+		}
+	}
+
+	if toggled, _ := rc5Timings(5, 12, true); reflect.DeepEqual(toggled, timings) {
+		t.Error("Flipping toggle didn't change the timings")
+	}
+
+	if _, err := rc5Timings(32, 0, false); err == nil {
+		t.Error("Address 32 should have been rejected")
+	}
+
+	if _, err := rc5Timings(0, 128, false); err == nil {
+		t.Error("Command 128 should have been rejected")
+	}
+}
@@ -0,0 +1,52 @@
+package orvibo
+
+// IR sequences, for when one button isn't enough (e.g. turning on the TV, then the amp, then switching the
+// amp's input). The codes are sent one after the other, with a gap between them so each device has time to act
+
+import (
+	"fmt"  // For building our error messages
+	"time" // For our gaps
+)
+
+// IRSequenceEvent is the Payload of the "irsequenceprogress" and "irsequencedone" events
+type IRSequenceEvent struct {
+	Device *Device // The AllOne sending the sequence
+	Code   IRCode  // The code that was just sent
+	Sent   int     // How many codes have been sent so far
+	Total  int     // How many codes are in the sequence
+}
+
+// EmitIRSequence emits each of codes from the AllOne with the MAC address macAdd, waiting gap between each one.
+// "irsequenceprogress" is raised after each code, and "irsequencedone" after the last. If a code can't be sent,
+// the rest of the sequence is abandoned and the error is returned. Pass Acknowledged() to wait for the AllOne to
+// confirm each code before moving on. It blocks until the sequence is finished, so use a goroutine if you need to
+func (c *Client) EmitIRSequence(macAdd string, codes []IRCode, gap time.Duration, opts ...CommandOption) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	o := getCommandOptions(opts)
+	for i, code := range codes {
+		if i > 0 { // Give the last device time to act
+			select {
+			case <-time.After(gap):
+			case <-c.closing:
+				return ErrClosed
+			}
+		}
+
+		if err := c.emitIR(code.Code, macAdd, o); err != nil {
+			return fmt.Errorf("Couldn't send code %d of %d (%s): %v", i+1, len(codes), code.Name, err)
+		}
+
+		snapshot := c.snapshot(device)
+		progress := IRSequenceEvent{Device: snapshot, Code: code, Sent: i + 1, Total: len(codes)}
+		c.passEvent("irsequenceprogress", snapshot, progress)
+		if i == len(codes)-1 {
+			c.passEvent("irsequencedone", snapshot, progress)
+		}
+	}
+
+	return nil
+}
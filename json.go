@@ -0,0 +1,112 @@
+package orvibo
+
+// JSON encoding, for REST APIs, log pipelines and the like built on top of us. Left to itself, encoding/json
+// writes a Device's IP address out as a struct and an event's Type as a number, so we tidy those up here.
+// IRCode already has JSON tags, so it doesn't need anything extra
+
+import (
+	"encoding/json" // For encoding and decoding
+	"reflect"       // For making payloads of the right type
+)
+
+// payloadTypes is what kind of Payload each event carries, so we can decode it back into the right type.
+// Events that aren't in here don't have a Payload
+var payloadTypes = map[EventType]reflect.Type{
+	EventStateChanged:        reflect.TypeOf(StateChangedEvent{}),
+	EventIRCode:              reflect.TypeOf(IRLearnedEvent{}),
+	EventRFCode:              reflect.TypeOf(RFLearnedEvent{}),
+	EventRFSwitch:            reflect.TypeOf(RFSwitchEvent{}),
+	EventRFSwitchPaired:      reflect.TypeOf(RFSwitchEvent{}),
+	EventRFStateChanged:      reflect.TypeOf(RFStateChangedEvent{}),
+	EventSocketFound:         reflect.TypeOf(DeviceFoundEvent{}),
+	EventAllOneFound:         reflect.TypeOf(DeviceFoundEvent{}),
+	EventExistingSocketFound: reflect.TypeOf(DeviceFoundEvent{}),
+	EventExistingAllOneFound: reflect.TypeOf(DeviceFoundEvent{}),
+	EventEventsDropped:       reflect.TypeOf(EventsDroppedEvent{}),
+	EventIRSequenceProgress:  reflect.TypeOf(IRSequenceEvent{}),
+	EventIRSequenceDone:      reflect.TypeOf(IRSequenceEvent{}),
+	EventCurtain:             reflect.TypeOf(CurtainEvent{}),
+	EventDeviceIPChanged:     reflect.TypeOf(DeviceIPChangedEvent{}),
+	EventParseError:          reflect.TypeOf(ParseErrorEvent{}),
+	EventListenerError:       reflect.TypeOf(ListenerErrorEvent{}),
+}
+
+// jsonDevice is a Device as it appears in JSON. The embedded fields are written out as they are, apart
+// from the ones we replace below
+type jsonDevice struct {
+	*plainDevice
+	IP             string `json:"IP"`                       // Just the address (e.g. 192.168.1.50). Devices are always on devicePort
+	RemotePassword string `json:"RemotePassword,omitempty"` // Never filled in, so the password doesn't end up in logs
+}
+
+// plainDevice is a Device without its MarshalJSON and UnmarshalJSON, so we don't call them again from inside themselves
+type plainDevice Device
+
+// MarshalJSON writes the device out with its IP address as a string. RemotePassword is left out
+func (device Device) MarshalJSON() ([]byte, error) {
+	out := jsonDevice{plainDevice: (*plainDevice)(&device)}
+	if device.IP != nil {
+		out.IP = device.IP.IP.String()
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads a device written out by MarshalJSON
+func (device *Device) UnmarshalJSON(data []byte) error {
+	in := jsonDevice{plainDevice: (*plainDevice)(device)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	device.IP = nil
+	if in.IP != "" {
+		addr, err := deviceAddr(in.IP)
+		if err != nil {
+			return err
+		}
+
+		device.IP = addr
+	}
+
+	return nil
+}
+
+// MarshalText writes the event type out as its name (e.g. "statechanged"), rather than a number
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText reads an event type from its name. Names we don't know are EventUnknown
+func (t *EventType) UnmarshalText(text []byte) error {
+	*t = eventType(string(text))
+	return nil
+}
+
+// UnmarshalJSON reads an event written out with encoding/json, decoding its Payload back into the
+// type the event carries (e.g. StateChangedEvent for "statechanged")
+func (event *EventStruct) UnmarshalJSON(data []byte) error {
+	type plainEvent EventStruct // Without this method, so we don't end up back here
+	in := struct {
+		*plainEvent
+		Payload json.RawMessage
+	}{plainEvent: (*plainEvent)(event)}
+
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	event.Payload = nil
+	payloadType, found := payloadTypes[event.Type]
+	if found == false || len(in.Payload) == 0 || string(in.Payload) == "null" { // Nothing we know how to decode
+		return nil
+	}
+
+	payload := reflect.New(payloadType)
+	if err := json.Unmarshal(in.Payload, payload.Interface()); err != nil {
+		return err
+	}
+
+	event.Payload = payload.Elem().Interface()
+	return nil
+}
@@ -0,0 +1,141 @@
+package orvibo
+
+// Learning sessions. EnterLearningMode just puts the AllOne into learning mode, and the code turns up later
+// as an "ircode" event. LearnIR wraps the whole thing up, for setup scripts and the like that just want the code.
+// PairRFSwitch and LearnCurtainCode do the same for RF switches and curtains.
+//
+// EXPERIMENTAL: we've never had a capture of an AllOne's reply to RF learning mode. Where the code sits in it
+// (and that it can be sent back out as-is by EmitRF) is a guess, so PairRFSwitch and LearnCurtainCode refuse to
+// run unless ExperimentalRF is set. If you have an AllOne and some RF gear, a capture would let us drop this
+
+import (
+	"context" // For our timeout
+	"errors"  // For crafting our own errors
+	"fmt"     // For building our error messages
+	"time"    // For our timeout
+)
+
+// ErrLearnTimeout is returned when an AllOne doesn't hear a code before the timeout runs out
+var ErrLearnTimeout = errors.New("No code was learned before the timeout ran out")
+
+// ExperimentalRF lets PairRFSwitch and LearnCurtainCode run. The layout of the AllOne's RF learning reply is
+// unverified, so the codes they store might not work. Shared by every Client
+var ExperimentalRF = false
+
+// ErrExperimentalRF is returned by PairRFSwitch and LearnCurtainCode when ExperimentalRF isn't set
+var ErrExperimentalRF = errors.New("RF learning is experimental. Set ExperimentalRF to try it")
+
+// LearnIR puts the AllOne with the MAC address macAdd into learning mode, then waits for you to press a button
+// on your remote, and returns the code it heard (as hex, ready for EmitIR or SaveIRCode). If nothing's heard
+// within timeout, ErrLearnTimeout is returned. There's no command to take an AllOne out of learning mode, but it
+// leaves by itself once it's heard a code, or after a while if it doesn't. Like WaitForDevice, it reads messages
+// itself unless Listen has been called
+func (c *Client) LearnIR(macAdd string, timeout time.Duration) (string, error) {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return "", err
+	}
+
+	if device.DeviceType != ALLONE {
+		return "", fmt.Errorf("%s isn't an AllOne, so it can't learn IR codes", macAdd)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c.devicesLock.RLock()
+	before := device.irCodesLearned // So we know when a new one comes in
+	c.devicesLock.RUnlock()
+
+	if err := c.EnterLearningMode(macAdd); err != nil {
+		return "", err
+	}
+
+	if err := c.waitUntil(ctx, func() bool {
+		c.devicesLock.RLock()
+		defer c.devicesLock.RUnlock()
+		return device.irCodesLearned != before
+	}); err != nil {
+		return "", err
+	}
+
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+	return device.LastIRMessage, nil
+}
+
+// PairRFSwitch pairs an Orvibo RF switch with the AllOne with the MAC address macAdd. It puts the AllOne into RF
+// learning mode, waits for you to press the switch, then stores the switch's code in RFSwitches[switchID] (switchID
+// is whatever you'd like to call it) and raises "rfswitchpaired". If nothing's heard within timeout,
+// ErrLearnTimeout is returned. Like LearnIR, it reads messages itself unless Listen has been called.
+// Experimental: returns ErrExperimentalRF unless ExperimentalRF is set
+func (c *Client) PairRFSwitch(macAdd string, switchID string, timeout time.Duration) (RFSwitch, error) {
+	device, code, err := c.learnRF(macAdd, timeout)
+	if err != nil {
+		return RFSwitch{}, err
+	}
+
+	c.devicesLock.Lock()
+	rfSwitch := device.RFSwitches[switchID]
+	rfSwitch.Code = code
+	device.RFSwitches[switchID] = rfSwitch
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passEvent("rfswitchpaired", snapshot, RFSwitchEvent{Device: snapshot, SwitchID: switchID, State: rfSwitch.State})
+	return rfSwitch, nil
+}
+
+// learnRF puts the AllOne with the MAC address macAdd into RF learning mode, and waits for it to hear a code
+func (c *Client) learnRF(macAdd string, timeout time.Duration) (*Device, string, error) {
+	if ExperimentalRF == false {
+		return nil, "", ErrExperimentalRF
+	}
+
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if device.DeviceType != ALLONE {
+		return nil, "", fmt.Errorf("%s isn't an AllOne, so it can't learn RF codes", macAdd)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c.devicesLock.RLock()
+	before := device.rfCodesLearned
+	c.devicesLock.RUnlock()
+
+	if err := c.EnterRFLearningMode(macAdd); err != nil {
+		return nil, "", err
+	}
+
+	if err := c.waitUntil(ctx, func() bool {
+		c.devicesLock.RLock()
+		defer c.devicesLock.RUnlock()
+		return device.rfCodesLearned != before
+	}); err != nil {
+		return nil, "", err
+	}
+
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+	return device, device.lastRFCode, nil
+}
+
+// waitUntil reads messages until done returns true. Returns ErrLearnTimeout if ctx runs out first
+func (c *Client) waitUntil(ctx context.Context, done func() bool) error {
+	for done() == false {
+		if ctx.Err() != nil {
+			return ErrLearnTimeout
+		}
+
+		if err := c.pumpMessages(ctx, waitPollInterval); err != nil { // Closed while we were waiting
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package orvibo
+
+// A background listener, so you don't have to call CheckForMessages in a loop yourself. It sits in a
+// blocking read on its own goroutine, and everything it hears turns up on Events as usual. If reading keeps
+// failing (the interface has gone away, say), it waits a little longer after each failure rather than spinning
+
+import (
+	"errors" // For crafting our own errors
+	"net"    // For telling timeouts from real errors
+	"time"   // For unblocking our read when we stop, and for backing off
+)
+
+const (
+	listenerBackoffMin = 10 * time.Millisecond // How long the listener waits after its first failed read
+	listenerBackoffMax = 5 * time.Second       // The longest it waits between failed reads
+)
+
+// Listen starts reading messages on a background goroutine, until Stop or Close is called.
+// Don't call CheckForMessages yourself while the listener is running
+func (c *Client) Listen() error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+
+	c.listenLock.Lock()
+	defer c.listenLock.Unlock()
+
+	if c.listenerDone != nil {
+		return errors.New("Already listening")
+	}
+
+	stop := make(chan bool)
+	done := make(chan bool)
+	c.stopListening = stop
+	c.listenerDone = done
+
+	go func() {
+		defer close(done)
+
+		failures := 0 // How many reads in a row have failed
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			_, readErr, _ := c.checkForMessages()
+			if readErr == ErrClosed { // Close was called, so there's nothing left to read
+				return
+			}
+
+			if readErr == nil || isTimeout(readErr) { // Stop knocks us out of our read with a timeout, which is fine
+				failures = 0
+				continue
+			}
+
+			if c.checkReady() != nil { // The read failed because we've been closed. Next time round returns
+				continue
+			}
+
+			failures++
+			getLogger().Warn("Couldn't read from our connection (%d in a row): %v", failures, readErr)
+			c.passEvent("listenererror", &Device{}, ListenerErrorEvent{Error: readErr.Error(), Failures: failures})
+
+			select { // Give whatever's wrong a chance to sort itself out
+			case <-time.After(listenerBackoff(failures)):
+			case <-stop:
+				return
+			case <-c.closing:
+				return
+			}
+		}
+	}()
+
+	c.passMessage("listening", &Device{})
+	return nil
+}
+
+// Stop stops the background listener started by Listen, and waits for it to finish. The connection stays
+// open, so you can go back to calling CheckForMessages yourself, or call Listen again
+func (c *Client) Stop() {
+	c.listenLock.Lock()
+	defer c.listenLock.Unlock()
+
+	if c.listenerDone == nil { // Not listening
+		return
+	}
+
+	close(c.stopListening)
+	if c.checkReady() == nil {
+		c.conn.SetReadDeadline(time.Now()) // Knock the listener out of its blocking read
+	}
+
+	<-c.listenerDone
+	if c.checkReady() == nil {
+		c.conn.SetReadDeadline(time.Time{}) // Back to blocking reads
+	}
+
+	c.stopListening = nil
+	c.listenerDone = nil
+	c.passMessage("stopped", &Device{})
+}
+
+// listening returns true if the background listener is running
+func (c *Client) listening() bool {
+	c.listenLock.Lock()
+	defer c.listenLock.Unlock()
+
+	return c.listenerDone != nil
+}
+
+// listenerBackoff is how long the listener waits after failures failed reads in a row. It doubles every time,
+// up to listenerBackoffMax
+func listenerBackoff(failures int) time.Duration {
+	backoff := listenerBackoffMin
+	for i := 1; i < failures && backoff < listenerBackoffMax; i++ {
+		backoff *= 2
+	}
+
+	if backoff > listenerBackoffMax {
+		backoff = listenerBackoffMax
+	}
+
+	return backoff
+}
+
+// isTimeout checks if err is a read deadline passing
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
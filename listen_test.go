@@ -0,0 +1,64 @@
+package orvibo_test
+
+import (
+	"errors"      // For our broken connection
+	"net"         // For addresses
+	"sync/atomic" // The listener counts reads on its own goroutine
+	"testing"
+	"time" // For timeouts
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/orvibotest"
+)
+
+// brokenTransport is a Transport whose reads fail until fixed is set
+type brokenTransport struct {
+	*orvibotest.Transport
+	reads int32 // How many times ReadFromUDP has been called
+	fixed int32 // Set to 1 to let reads through again
+}
+
+func (t *brokenTransport) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	atomic.AddInt32(&t.reads, 1)
+	if atomic.LoadInt32(&t.fixed) == 0 {
+		return 0, nil, errors.New("network is down")
+	}
+
+	return t.Transport.ReadFromUDP(b)
+}
+
+func TestListenerBacksOff(t *testing.T) {
+	transport := &brokenTransport{Transport: orvibotest.NewTransport()}
+	c := orvibo.NewClient(orvibo.WithTransport(transport))
+	if _, err := c.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Listen(); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	failed := expectEvent(t, c, "listenererror").Payload.(orvibo.ListenerErrorEvent)
+	if failed.Error != "network is down" || failed.Failures != 1 {
+		t.Errorf("Got %+v, not the first failure", failed)
+	}
+
+	// Without backing off, this would be millions of reads
+	time.Sleep(200 * time.Millisecond)
+	if reads := atomic.LoadInt32(&transport.reads); reads > 10 {
+		t.Errorf("Read %d times in 200ms. The listener isn't backing off", reads)
+	}
+
+	// Once reading works again, so does everything else
+	atomic.StoreInt32(&transport.fixed, 1)
+	transport.Deliver(discoveryReply(t, testSocket, "SOC002", "01"), testAddr)
+	expectEvent(t, c, "socketfound")
+
+	// And Close doesn't have to wait for a backoff to finish
+	start := time.Now()
+	c.Close()
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Close took %s", waited)
+	}
+}
@@ -0,0 +1,122 @@
+package orvibo
+
+// Logging. By default we don't log anything, so we don't clutter up the output of daemons and the like. Set a
+// Logger with SetLogger to find out what's going on inside, and set LogPackets to see every packet as well
+
+import (
+	"encoding/hex" // For dumping packets
+	"fmt"          // For formatting log lines
+	"io"           // So we can log to files, stderr etc.
+	"log"          // For timestamps
+	"net"          // For knowing who a packet was to or from
+	"sync"         // The logger can be changed while we're logging
+)
+
+// Logger is anything that can take our log messages. Each method takes a format and arguments, like fmt.Printf
+type Logger interface {
+	Debug(format string, args ...interface{})
+	Info(format string, args ...interface{})
+	Warn(format string, args ...interface{})
+	Error(format string, args ...interface{})
+}
+
+// LogLevel is how important a log message is
+type LogLevel int
+
+// Our log levels, least important first
+const (
+	LogDebug LogLevel = iota // Every packet (if LogPackets is set) and other detail
+	LogInfo                  // Devices coming and going
+	LogWarn                  // Things that went wrong but we carried on from (e.g. a message we couldn't read)
+	LogError                 // Things that went wrong that you probably need to look at
+)
+
+// LogPackets, if true, logs a hex dump of every packet we send and receive at Debug level
+var LogPackets = false
+
+var logger Logger = nopLogger{} // Where our log messages go. Nowhere, until SetLogger is called
+var loggerLock sync.RWMutex     // The logger can be changed while we're logging
+
+// SetLogger sends our log messages to l. Like Trace, it's shared by every Client. Pass nil to stop logging
+func SetLogger(l Logger) {
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+
+	if l == nil {
+		l = nopLogger{}
+	}
+
+	logger = l
+}
+
+// NewLogger returns a Logger that writes a timestamped line to w for every message at level or above, for when
+// you don't already have a logging package you'd rather use
+func NewLogger(w io.Writer, level LogLevel) Logger {
+	return &writerLogger{out: log.New(w, "", log.LstdFlags), level: level}
+}
+
+// getLogger returns the Logger set with SetLogger
+func getLogger() Logger {
+	loggerLock.RLock()
+	defer loggerLock.RUnlock()
+
+	return logger
+}
+
+// logPacket logs a hex dump of a packet at Debug level, if LogPackets is set
+func logPacket(direction string, message string, addr *net.UDPAddr) {
+	if LogPackets == false {
+		return
+	}
+
+	data, err := hex.DecodeString(message)
+	if err != nil {
+		return
+	}
+
+	peer := ""
+	if addr != nil {
+		peer = addr.String()
+	}
+
+	getLogger().Debug("Packet %s (%s), %d bytes:\n%s", direction, peer, len(data), hex.Dump(data))
+}
+
+// nopLogger throws everything away
+type nopLogger struct{}
+
+func (nopLogger) Debug(format string, args ...interface{}) {}
+func (nopLogger) Info(format string, args ...interface{})  {}
+func (nopLogger) Warn(format string, args ...interface{})  {}
+func (nopLogger) Error(format string, args ...interface{}) {}
+
+// writerLogger is the Logger NewLogger returns
+type writerLogger struct {
+	out   *log.Logger
+	level LogLevel
+}
+
+func (l *writerLogger) Debug(format string, args ...interface{}) {
+	l.write(LogDebug, "DEBUG", format, args)
+}
+
+func (l *writerLogger) Info(format string, args ...interface{}) {
+	l.write(LogInfo, "INFO", format, args)
+}
+
+func (l *writerLogger) Warn(format string, args ...interface{}) {
+	l.write(LogWarn, "WARN", format, args)
+}
+
+func (l *writerLogger) Error(format string, args ...interface{}) {
+	l.write(LogError, "ERROR", format, args)
+}
+
+// write writes out a message, unless it's below our level
+func (l *writerLogger) write(level LogLevel, label string, format string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	l.out.Printf("[%s] %s", label, fmt.Sprintf(format, args...))
+}
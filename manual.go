@@ -0,0 +1,86 @@
+package orvibo
+
+// Finding devices without a broadcast. Broadcasts don't get through on a lot of segmented, VLAN'd or Docker
+// networks, even though the devices themselves are perfectly reachable. If you know where a device is, you
+// can ask it directly with AddDeviceByIP, or skip discovery altogether with RegisterDevice
+
+import (
+	"encoding/hex" // For checking MAC addresses
+	"fmt"          // For building our error messages
+	"net"          // For resolving IP addresses
+	"strconv"      // For port numbers
+	"time"         // For LastSeen
+
+	"github.com/Grayda/go-orvibo/packet" // For our discovery message
+)
+
+// AddDeviceByIP sends a discovery message straight to ip, rather than broadcasting it. The device's reply
+// is dealt with like any other discovery reply, so it raises the usual found events (and is subscribed
+// to, if AutoSubscribe is on)
+func (c *Client) AddDeviceByIP(ip string) error {
+	addr, err := deviceAddr(ip)
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.SendMessage(packet.DiscoverAll, &Device{IP: addr}); err != nil {
+		return err
+	}
+
+	c.passMessage("discover", &Device{IP: addr})
+	return nil
+}
+
+// RegisterDevice adds a device to Devices without hearing from it first, for networks where even unicast
+// discovery doesn't work. deviceType is SOCKET or ALLONE. It raises the usual found event and is subscribed
+// to if AutoSubscribe is on, so it carries on from there like any other device. If we already know about
+// the device, its IP address is updated instead. Either way, the Device returned is a copy, like GetDevice's
+func (c *Client) RegisterDevice(macAdd string, ip string, deviceType int) (*Device, error) {
+	if _, err := hex.DecodeString(macAdd); err != nil || len(macAdd) != 12 {
+		return nil, fmt.Errorf("%q isn't a MAC address. It should be 12 hex characters, like accf23aabbcc", macAdd)
+	}
+
+	if deviceType != SOCKET && deviceType != ALLONE {
+		return nil, fmt.Errorf("Can't register a device of type %d. Only SOCKET and ALLONE are supported", deviceType)
+	}
+
+	addr, err := deviceAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	c.devicesLock.Lock()
+	if device, found := c.Devices[macAdd]; found {
+		device.IP = addr
+		associateInterface(device)
+		snapshot := device.copy()
+		c.devicesLock.Unlock()
+		return snapshot, nil
+	}
+
+	c.deviceCount++
+	device := &Device{
+		ID:         c.deviceCount,
+		DeviceType: deviceType,
+		IP:         addr,
+		MACAddress: macAdd,
+		RFSwitches: make(map[string]RFSwitch),
+		Curtains:   make(map[string]Curtain),
+		LastSeen:   time.Now(), // We haven't actually heard from it, but this stops PurgeStaleDevices throwing it away straight away
+	}
+
+	c.Devices[macAdd] = device
+	c.devicesLock.Unlock()
+
+	c.addDevice(device)
+	return c.snapshot(device), nil
+}
+
+// deviceAddr turns an IP address into the UDP address we talk to devices on
+func deviceAddr(ip string) (*net.UDPAddr, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("%q isn't an IP address", ip)
+	}
+
+	return net.ResolveUDPAddr("udp4", net.JoinHostPort(ip, strconv.Itoa(devicePort)))
+}
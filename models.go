@@ -0,0 +1,55 @@
+package orvibo
+
+// Every device tells us its model in its discovery reply, which packet.Model reads out (e.g. SOC002 for an S20, IRD014 for an AllOne).
+// We look that up in a registry to work out what kind of device it is, rather than sniffing for substrings. If you've
+// got a device that works like one we know but reports a model we don't, add it with RegisterModel
+
+import (
+	"errors"  // For crafting our own errors
+	"fmt"     // For building our error messages
+	"strings" // For prefix matching
+	"sync"    // Models can be registered while we're discovering
+)
+
+// modelTypes maps the start of a model string to the kind of device it is. When more than one prefix
+// matches, the longest wins, so a specific model can be mapped differently to the rest of its family
+var modelTypes = map[string]int{
+	"SOC": SOCKET, // S10, S20 and later socket revisions (SOC002, SOC005 etc.)
+	"IRD": ALLONE, // The AllOne (IRD014 etc.)
+}
+
+var modelTypesLock sync.RWMutex // Models can be registered while we're discovering
+
+// RegisterModel maps model strings starting with prefix (e.g. "SOC006") to deviceType, replacing any mapping
+// that prefix already had. deviceType is SOCKET or ALLONE, or UNKNOWN to leave devices with that model out of Devices. Devices
+// that have already been found keep the type they were found with
+func RegisterModel(prefix string, deviceType int) error {
+	if prefix == "" {
+		return errors.New("Model prefixes can't be empty")
+	}
+
+	if deviceType != SOCKET && deviceType != ALLONE && deviceType != UNKNOWN {
+		return fmt.Errorf("Can't register a model as type %d. Only SOCKET, ALLONE and UNKNOWN are supported", deviceType)
+	}
+
+	modelTypesLock.Lock()
+	defer modelTypesLock.Unlock()
+
+	modelTypes[prefix] = deviceType
+	return nil
+}
+
+// modelType looks up what kind of device a model string belongs to. Returns UNKNOWN if we don't know it
+func modelType(model string) int {
+	modelTypesLock.RLock()
+	defer modelTypesLock.RUnlock()
+
+	deviceType, longest := UNKNOWN, 0
+	for prefix, t := range modelTypes {
+		if strings.HasPrefix(model, prefix) && len(prefix) > longest {
+			deviceType, longest = t, len(prefix)
+		}
+	}
+
+	return deviceType
+}
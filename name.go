@@ -0,0 +1,45 @@
+package orvibo
+
+// Device names live in a fixed 16 byte field in table 4, padded out with spaces. The packet package does the
+// actual work of encoding them
+
+import (
+	"github.com/Grayda/go-orvibo/packet" // For the name field itself
+)
+
+// MaxNameLength is how many bytes a device name can be. Non-English characters take up more than one byte each
+const MaxNameLength = packet.NameLength
+
+// ValidateName checks that a name will fit on a device. Returns a descriptive error if it won't
+func ValidateName(name string) error {
+	return packet.ValidateName(name)
+}
+
+// SetDeviceName renames a device by writing the new name into its table 4 record. Waits for the device to
+// accept it (reading messages itself unless Listen has been called), then raises "namechanged"
+func (c *Client) SetDeviceName(macAdd string, name string) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := packet.EncodeName(name)
+	if err != nil {
+		return err
+	}
+
+	err = c.modifyTableFour("SetDeviceName", device, func(record string) (string, error) {
+		return setField(record, nameField, encoded)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.devicesLock.Lock()
+	device.Name = name
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("namechanged", snapshot)
+	return nil
+}
@@ -0,0 +1,43 @@
+package orvibo
+
+import (
+	"context" // For acknowledgements that wait on a context
+)
+
+// CommandOption changes how a single command (SetState, EmitIR etc.) is sent. Pass as many as you like
+// to the end of the call, e.g. SetState(macAdd, true, DryRun())
+type CommandOption func(*commandOptions)
+
+// commandOptions holds the result of applying all the CommandOptions for one call
+type commandOptions struct {
+	dryRun   bool            // Go through the motions, but don't actually send anything
+	critical bool            // Send even if the device is in a quiet window
+	ack      bool            // Wait for the device to acknowledge the command, and retry if it doesn't
+	ctx      context.Context // With ack, keep retrying until this is done, instead of giving up after AckRetries
+}
+
+// DryRun makes a command go through validation, the audit log and events as normal, but skips the actual UDP write.
+// Handy for previewing what a sequence of commands would do, or testing rules without clicking relays
+func DryRun() CommandOption {
+	return func(o *commandOptions) {
+		o.dryRun = true
+	}
+}
+
+// acknowledgedUntil makes a command wait for the device to acknowledge it, retrying until ctx is done
+func acknowledgedUntil(ctx context.Context) CommandOption {
+	return func(o *commandOptions) {
+		o.ack = true
+		o.ctx = ctx
+	}
+}
+
+// getCommandOptions applies a list of CommandOptions and returns the result
+func getCommandOptions(opts []CommandOption) commandOptions {
+	var o commandOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
@@ -4,35 +4,53 @@ package orvibo
 // including the AllOne IR / 433mhz blaster and the S10 / S20 sockets
 
 import (
-	"encoding/hex" // For converting stuff to and from hex
-	"errors"       // For crafting our own errors
-	"fmt"          // For outputting stuff
-	"math/rand"    // For the generation of random numbers
-	"net"          // For networking stuff
+	"context"       // For listening with socket options
+	"encoding/hex"  // For converting stuff to and from hex
+	"encoding/json" // For listing devices
+	"errors"        // For crafting our own errors
+	"fmt"           // For outputting stuff
+	"math/rand"     // For the generation of random numbers
+	"net"           // For networking stuff
+	"runtime/debug" // For logging where a handler panicked
 	"strconv"
-	"strings" // For string manipulation (indexOf etc.)
+	"sync/atomic" // For checking whether we're prepared
+	"time"        // For keeping track of device clocks
 
-	"github.com/davecgh/go-spew/spew" // For neatly outputting stuff
+	"github.com/Grayda/go-orvibo/packet" // For building and reading packets
 )
 
 // EventStruct is our equivalent to node.js's Emitters, of sorts.
 // This basically passes back to our Event channel, info about what event was raised
 // (e.g. Device, plus an event name) so we can act appropriately
 type EventStruct struct {
-	Name       string
+	Name       string    // The legacy name of the event (e.g. "statechanged"). Same as Type.String()
+	Type       EventType // What kind of event it is
 	DeviceInfo *Device
+	Payload    interface{} // Extra details for some events (e.g. a StateChangedEvent for EventStateChanged). nil if there aren't any
+	Count      int         // How many times this event happened. 1, unless CoalesceEvents rolled repeats up into it, in which case it's how many repeats it stands for
 }
 
-// IRCode is a struct that describes our IR code. Name is a short name (e.g. "Power On") and Code is an IR hex string
+// IRCode is a struct that describes our IR code. Name is a short name (e.g. "Power On") and Code is an IR hex string.
+// See SaveIRCode for keeping them in the IR code library
 type IRCode struct {
-	ID   int
-	Name string
-	Code string
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Code string `json:"code"`
 }
 
 // RFSwitch contains info about RF switches. Access it through Device[macAdd].RFSwitches[switchID].State
 type RFSwitch struct {
 	State bool
+	Code  string // The RF code for the switch, as hex, if it's been paired with PairRFSwitch
+}
+
+// Curtain contains info about an RF curtain motor or roller blind. Access it through Device[macAdd].Curtains[curtainID].
+// Unlike switches, curtain motors have a separate code for each button on their remote. See LearnCurtainCode
+type Curtain struct {
+	OpenCode   string        // The RF code that opens the curtain, as hex
+	CloseCode  string        // The RF code that closes the curtain, as hex
+	StopCode   string        // The RF code that stops the curtain where it is, as hex
+	LastAction CurtainAction // The last thing we told the curtain to do. Curtains don't report back, so this is our best guess at what it's doing
 }
 
 // Device is info about the type of device that's been detected (socket, allone etc.)
@@ -40,15 +58,51 @@ type Device struct {
 	ID            int          // The ID of our socket
 	Name          string       // The name of our item
 	DeviceType    int          // What type of device this is. See the const below for valid types
+	Model         string       // The model the device reported when we discovered it (e.g. SOC002)
 	IP            *net.UDPAddr // The IP address of our item
 	MACAddress    string       // The MAC Address of our item. Necessary for controlling the S10 / S20 / AllOne
 	Subscribed    bool         // Have we subscribed to this item yet? Doing so lets us control
 	Queried       bool         // Have we queried this item for it's name and details yet?
 	State         bool         // Is the item turned on or off? Will always be "false" for the AllOne, which doesn't do states, just IR & 433
 	RFSwitches    map[string]RFSwitch
-	LastIRMessage string // Not yet implemented.
-	LastMessage   string // The last message to come through for this device
-
+	Curtains      map[string]Curtain // Curtain motors and roller blinds the AllOne controls. Will be empty for anything else
+	LastIRMessage string             // Not yet implemented.
+	LastMessage   string             // The last message to come through for this device
+	LastSeen      time.Time          // When we last heard from this device
+	Interface     string             // The name of the network interface we found this device on (e.g. eth0)
+	DeviceTime    time.Time          // The device's own clock, as of its last discovery reply
+	ClockDrift    time.Duration      // How far the device's clock is ahead of ours (negative if it's behind)
+	Tripped       bool               // Has this device failed so many times we've stopped sending to it? See BreakerThreshold
+	Offline       bool               // Have we gone OfflineAfter without hearing from this device? See StartHealthMonitor
+
+	LastSubscribed time.Time // When the device last confirmed our subscription. See AutoResubscribe
+
+	CountdownRemaining time.Duration // How long until the socket's auto-off countdown turns it off. 0 if there's no countdown
+	CountdownUpdated   time.Time     // When we read CountdownRemaining. Use CountdownEnds to work out when it'll actually finish
+	Timers             []Timer       // The socket's timers, as of the last GetTimers
+
+	// Details from the device's table 4 record, as of the last time we queried it
+	RemotePassword      string // The password the WiWo app uses to control the device from outside your network
+	HardwareVersion     uint32 // The device's hardware version
+	FirmwareVersion     uint32 // The device's firmware version
+	WifiFirmwareVersion uint32 // The version of the firmware on the device's Wi-Fi chip
+	DHCP                bool   // Does the device get its IP address via DHCP?
+	Discoverable        bool   // Does the device answer discovery broadcasts?
+	TimezoneSet         bool   // Has the device's timezone been set?
+	Timezone            int    // The device's timezone, in hours from UTC
+
+	confirmedState bool      // The last state the device itself told us about. State is set as soon as we ask for a change, this isn't
+	clockSeen      time.Time // When we got the discovery reply that DeviceTime came from
+	rebooted       bool      // Set when we think the device has restarted, until it's subscribed again
+	ifIndex        int       // The index of Interface, for sending packets back out of it
+	failures       int       // How many times in a row sending to this device has failed
+	trippedAt      time.Time // When the breaker tripped, or when we last let a probe through
+	probing        bool      // Set while probeBreaker is checking on the device, so there's only ever one
+	tableFour      string    // The device's table 4 record (as hex), as of the last time we queried it
+	renewing       bool      // Set when we've asked to renew our subscription, until the device confirms it
+	irCodesLearned int       // How many IR codes the device has sent us, so LearnIR can tell when a new one arrives
+	rfCodesLearned int       // How many RF codes the device has sent us, so PairRFSwitch can tell when a new one arrives
+	lastRFCode     string    // The last RF code the device learned
 }
 
 const (
@@ -59,122 +113,300 @@ const (
 	KEPLER              // KEPLER is Orvibo's latest product, a timer / gas detector. Not yet implemented
 )
 
+// EventBufferSize is how many events can be waiting on Events (or on the Events of a new Client) before
+// they start being dropped. It's used when the channel is made, so to change the size of the package-level
+// Events, replace it: orvibo.Events = make(chan orvibo.EventStruct, 1000)
+var EventBufferSize = 64
+
 // Events holds the events we'll be passing back to our calling code.
-var Events = make(chan EventStruct, 1) // Events is our events channel which will notify calling code that we have an event happening
-var Devices = make(map[string]*Device) // All the Devices we've discovered
-var twenties = "202020202020"          // This is padding for the MAC Address. It appears often, so we define it here for brevity
-var deviceCount int                    // How many items we've discovered
-var conn *net.UDPConn                  // UDP Connection
-// Our UDP connection
+var Events = make(chan EventStruct, EventBufferSize) // Events is our events channel which will notify calling code that we have an event happening
+var Devices = make(map[string]*Device)               // All the Devices we've discovered. Use GetDevice or GetDevices while we're listening
+var twenties = packet.Padding                        // This is padding for the MAC Address. It appears often, so we define it here for brevity
+
+// RawStateEvents, if true, raises "statechanged" for every state confirmation the device sends,
+// even if it's the same state we were already told about. Sockets tend to repeat themselves, so this is off by default
+var RawStateEvents = false
+
+// ReceiveBufferSize is the largest message (in bytes) we can read. Learned IR codes can get quite long, so this is generous.
+// Anything bigger is flagged with a "messagetruncated" event rather than being half-parsed
+var ReceiveBufferSize = 8192
+
+// AutoSubscribe, if true, subscribes to each new device as soon as it's discovered, so you don't have to call Subscribe() yourself
+var AutoSubscribe = false
+
+// AutoQuery, if true, queries each device as soon as its subscription is confirmed. Either way, once a device
+// is both subscribed and queried (so we know its name and its state), we raise "deviceready"
+var AutoQuery = false
 
 // ===============
 // Exported Events
 // ===============
 
-// Prepare is the first function you should call. Gets our UDP connection ready
-func Prepare() (bool, error) {
+// Prepare is the first function you should call. Gets our UDP connection ready. Any options
+// (e.g. WithInterface or WithBindAddr) are applied to the Client first
+func (c *Client) Prepare(opts ...ClientOption) (bool, error) {
+	switch atomic.LoadInt32(&c.state) {
+	case stateReady: // Already done
+		return true, nil
+	case stateClosed: // We don't come back from Close
+		return false, ErrClosed
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
 
-	_, err := getLocalIP() // Get our local IP. Used to test if there is a network connection issue
-	if err != nil {        // Error? Return false
+	if c.transport != nil { // Someone else is doing our networking (e.g. a fake one in a test), so there's nothing to bind
+		c.conn = c.transport
+	} else if err := c.bind(); err != nil {
 		return false, err
 	}
 
-	udpAddr, resolveErr := net.ResolveUDPAddr("udp4", ":10000") // Get our address ready for listening
-	if resolveErr != nil {
-		return false, resolveErr
+	atomic.StoreInt32(&c.state, stateReady)
+	go c.manageSubscriptions() // Stops when we're closed
+	c.passMessage("ready", &Device{})
+	return true, nil
+}
+
+// bind opens our UDP connection, on the address and port we've been told to use (10000 on every interface, by default)
+func (c *Client) bind() error {
+	ip, err := c.localIP() // Get our local IP. Used to test if there is a network connection issue
+	if err != nil {
+		return err
 	}
+	c.ownIP = ip // So we can ignore our own broadcasts
 
-	var listenErr error
-	conn, listenErr = net.ListenUDP("udp", udpAddr) // Now we listen on the address we just resolved
-	if listenErr != nil {
-		return false, listenErr
+	bindAddr, err := c.bindAddr()
+	if err != nil {
+		return err
 	}
-	passMessage("ready", &Device{})
-	return true, nil
+
+	udpAddr, err := net.ResolveUDPAddr("udp4", bindAddr) // Get our address ready for listening
+	if err != nil {
+		return err
+	}
+
+	if c.ReusePort { // Share the port with anyone else who's willing to
+		listenConfig := net.ListenConfig{Control: reuseControl}
+		packetConn, err := listenConfig.ListenPacket(context.Background(), "udp4", udpAddr.String())
+		if err != nil {
+			return err
+		}
+
+		c.conn = packetConn.(*net.UDPConn)
+		return nil
+	}
+
+	conn, err := net.ListenUDP("udp", udpAddr) // Now we listen on the address we just resolved
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	return nil
 }
 
 // Discover is a function that broadcasts 686400067161 over the network in order to find unpaired networks
-func Discover() {
+func (c *Client) Discover() {
 	// Wondering why we don't return anything? setInterval in our calling code can't handle returns
-	_, err := broadcastMessage("686400067161")
+	c.PurgeStaleDevices() // Clean out anything we haven't heard from in a long time (if DeviceTTL is set)
+
+	_, err := c.broadcastMessage(packet.DiscoverAll)
 	if err != nil {
 		return
 	}
-	passMessage("discover", &Device{})
+	c.passMessage("discover", &Device{})
 	return
 
 }
 
-// Subscribe loops over all the Devices we know about, and asks for control (subscription)
-func Subscribe() {
-	for k := range Devices { // Loop over all sockets we know about
-		//if Devices[k].Subscribed == false { // If we haven't subscribed.
-		// We send a message to each socket. reverseMAC takes a MAC address and reverses each pair (e.g. AC CF 23 becomes CA FC 32)
-		SendMessage("6864001e636c"+Devices[k].MACAddress+twenties+reverseMAC(Devices[k].MACAddress)+twenties, Devices[k])
-		//}
+// DiscoverDevice looks for one device by its MAC address (e.g. to find a socket again after it's rebooted or changed IP).
+// Only that device answers, and its reply raises the same found events as Discover
+func (c *Client) DiscoverDevice(macAdd string) error {
+	if len(macAdd) != 12 {
+		return fmt.Errorf("%q isn't a MAC address. It should be 12 hex characters, like accf23aabbcc", macAdd)
 	}
 
-	passMessage("subscribe", &Device{})
-	// FIXME: success will be the last socket subscribed. If all fail but this one, will return true. If all succeed except last one, will return false
-	return
+	msg, err := packet.NewPacket(packet.DiscoverMAC, macAdd, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.broadcastMessage(msg); err != nil { // We might not know (or might have the wrong) IP, so we broadcast
+		return err
+	}
+
+	c.passMessage("discover", &Device{MACAddress: macAdd})
+	return nil
 }
 
-// Query asks all the sockets we know about, for their names. Current state is sent on Subscription confirmation, not here
-func Query() (bool, error) {
-	var success bool
-	var err error
+// Subscribe loops over all the Devices we know about, and asks for control (subscription). Returns the result
+// for each device, keyed by MAC address. A nil error means the request went out; the device confirms with "subscribed"
+func (c *Client) Subscribe() map[string]error {
+	results := make(map[string]error)
+	for _, device := range c.deviceList() { // Loop over all sockets we know about
+		_, results[device.MACAddress] = c.subscribeDevice(device)
+	}
+
+	c.passMessage("subscribe", &Device{})
+	return results
+}
 
-	for k := range Devices { // Loop over all sockets we know about
-		if Devices[k].Queried == false && Devices[k].Subscribed == true { // If we've subscribed but not queried..
-			success, err = SendMessage("6864001D7274"+Devices[k].MACAddress+twenties+"0000000004000000000000", Devices[k])
+// Query asks all the sockets we've subscribed to but haven't queried yet, for their names. Current state is sent on
+// Subscription confirmation, not here. Returns the result for each device we asked, keyed by MAC address
+func (c *Client) Query() map[string]error {
+	results := make(map[string]error)
+	for _, device := range c.deviceList() { // Loop over all sockets we know about
+		c.devicesLock.RLock()
+		due := device.Queried == false && device.Subscribed == true // If we've subscribed but not queried..
+		c.devicesLock.RUnlock()
+
+		if due {
+			_, results[device.MACAddress] = c.queryDevice(device)
 		}
 	}
-	passMessage("query", &Device{})
-	return success, err
+
+	c.passMessage("query", &Device{})
+	return results
 }
 
-// ListDevices spews out info about all the Devices we know about. It's great because it includes counts and other stuff
-func ListDevices() {
-	spew.Dump(&Devices)
+// SubscribeDevice subscribes to a single device, rather than everything we know about. Its confirmation
+// raises "subscribed" as usual
+func (c *Client) SubscribeDevice(macAdd string) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.subscribeDevice(device)
+	return err
+}
+
+// QueryDevice asks a single device for its details (including its name), whether or not we've queried it before.
+// Its reply raises "queried" as usual
+func (c *Client) QueryDevice(macAdd string) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.queryDevice(device)
+	return err
+}
+
+// ListDevices logs every Device we know about (as JSON) at Info level. Set a Logger with SetLogger to see them
+func (c *Client) ListDevices() {
+	devices := c.GetDevices() // Copies, so we're not holding the lock while we log
+	for _, macAdd := range sortedMACs(devices) {
+		device, err := json.Marshal(devices[macAdd])
+		if err != nil {
+			getLogger().Warn("Couldn't list %s: %v", macAdd, err)
+			continue
+		}
+
+		getLogger().Info("Device %s: %s", macAdd, device)
+	}
 }
 
 // CheckForMessages does what it says on the tin -- checks for incoming UDP messages
-func CheckForMessages() (bool, error) { // Now we're checking for messages
+func (c *Client) CheckForMessages() (bool, error) { // Now we're checking for messages
+	success, _, err := c.checkForMessages()
+	return success, err
+}
+
+// checkForMessages is CheckForMessages, but it also returns the error from reading the connection (if any),
+// which CheckForMessages has never passed on. The listener uses it to tell a broken connection from a bad packet
+func (c *Client) checkForMessages() (bool, error, error) {
 
-	var msg []byte     // Holds the incoming message
-	var buf [1024]byte // We want to get 1024 bytes of messages (is this enough? Need to check!)
+	var msg []byte // Holds the incoming message
 
 	var success bool
 	var err error
 
-	n, addr, _ := conn.ReadFromUDP(buf[0:]) // Read 1024 bytes from the buffer
-	ip, _ := getLocalIP()                   // Get our local IP
-	if n > 0 && addr.IP.String() != ip {    // If we've got more than 0 bytes and it's not from us
+	if err = c.checkReady(); err != nil {
+		return false, err, err
+	}
+
+	if len(c.readBuffer) != c.ReceiveBufferSize+1 { // One extra byte, so we can tell if a message didn't fit
+		c.readBuffer = make([]byte, c.ReceiveBufferSize+1)
+	}
+
+	n, addr, readErr := c.conn.ReadFromUDP(c.readBuffer) // Read as much as our buffer will hold
+	if n > 0 && addr.IP.String() != c.ownIP {            // If we've got more than 0 bytes and it's not from us
 
-		msg = buf[0:n]                                              // n is how many bytes we grabbed from UDP
-		success, err = handleMessage(hex.EncodeToString(msg), addr) // Hand it off to our handleMessage func. We pass on the message and the address (for replying to messages)
-		msg = nil                                                   // Clear out our msg property so we don't run handleMessage on old data
+		msg = c.readBuffer[0:n] // n is how many bytes we grabbed from UDP
+		success, err = c.receive(msg, addr)
+		msg = nil // Clear out our msg property so we don't run handleMessage on old data
 	} else {
 		msg = nil
 	}
 
+	return success, readErr, err
+}
+
+// receive deals with a packet we've received: it traces, logs and taps it, makes sure it's all there, and hands it to handleMessage
+func (c *Client) receive(msg []byte, addr *net.UDPAddr) (bool, error) {
+	traceFrame("in", hex.EncodeToString(msg), addr)
+	logPacket("in", hex.EncodeToString(msg), addr)
+	c.tapPacket(Inbound, msg, addr)
+	if c.truncated(msg) { // Part of the message is missing, so don't try and parse it (we'd end up with half an IR code)
+		c.passMessage("messagetruncated", &Device{IP: addr, LastMessage: hex.EncodeToString(msg)})
+		err := fmt.Errorf("Message from %s was truncated at %d bytes. Try a larger ReceiveBufferSize", addr.String(), len(msg))
+		getLogger().Warn("%v", err)
+		return false, err
+	}
+
+	success, err := c.handleMessage(hex.EncodeToString(msg), addr) // Hand it off to our handleMessage func. We pass on the message and the address (for replying to messages)
+	if err != nil {
+		getLogger().Warn("Couldn't handle message from %s: %v", addr.String(), err)
+	}
+
 	return success, err
 }
 
+// truncated checks if a message we've read is shorter than it should be, either because it didn't fit
+// in our buffer, or because it's shorter than the length in its own header (the two bytes after 6864)
+func (c *Client) truncated(msg []byte) bool {
+	if len(msg) > c.ReceiveBufferSize {
+		return true
+	}
+
+	if len(msg) >= 4 && msg[0] == 0x68 && msg[1] == 0x64 {
+		declared := int(msg[2])<<8 | int(msg[3])
+		return len(msg) < declared
+	}
+
+	return false
+}
+
 // ToggleState finds out if the socket is on or off, then toggles it
-func ToggleState(macAdd string) (bool, error) {
-	if Devices[macAdd].State == true {
-		return SetState(macAdd, false)
+func (c *Client) ToggleState(macAdd string, opts ...CommandOption) (bool, error) {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return false, err
+	}
+
+	c.devicesLock.RLock()
+	on := device.State
+	c.devicesLock.RUnlock()
+
+	if on == true {
+		return c.SetState(macAdd, false, opts...)
 	}
 
-	return SetState(macAdd, true)
+	return c.SetState(macAdd, true, opts...)
 
 }
 
 // SetState sets the state of a socket, given its MAC address
-func SetState(macAdd string, state bool) (bool, error) {
-	if Devices[macAdd].DeviceType == SOCKET { // If it's a socket
-		Devices[macAdd].State = state
+func (c *Client) SetState(macAdd string, state bool, opts ...CommandOption) (bool, error) {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return false, err
+	}
+
+	if device.DeviceType == SOCKET { // If it's a socket
+		o := getCommandOptions(opts)
 		var statebit string
 		if state == true {
 			statebit = "01"
@@ -182,43 +414,110 @@ func SetState(macAdd string, state bool) (bool, error) {
 			statebit = "00"
 		}
 
-		success, err := SendMessage("686400176463"+macAdd+twenties+"00000000"+statebit, Devices[macAdd])
-		passMessage("stateset", Devices[macAdd])
+		msg, err := packet.NewPacket(packet.StateControl, macAdd, "00000000"+statebit)
+		if err != nil {
+			return false, err
+		}
+
+		success, err := c.sendCommand("SetState", msg, device, o, packet.StateChanged, func(message string) bool {
+			return packet.State(message) == state // The socket confirms with its new state
+		})
+		if success == false { // Didn't go out (e.g. quiet window), so the state hasn't changed
+			return success, err
+		}
+
+		if o.dryRun == true { // A dry run shouldn't change what we know about the socket, so we hand back a copy with the new state
+			preview := c.snapshot(device)
+			preview.State = state
+			c.passMessage("stateset", preview)
+			return success, err
+		}
+
+		c.devicesLock.Lock()
+		device.State = state
+		snapshot := device.copy()
+		c.devicesLock.Unlock()
+
+		c.passMessage("stateset", snapshot)
 		return success, err
 	}
 	return false, errors.New("Can't set state on a non-socket") // Naughty us, trying to set state on an AllOne!
 
 }
 
-// EmitIR emits IR from the AllOne. Takes a hex string
-func EmitIR(IR string, macAdd string) {
+// EmitIR emits IR from the AllOne with the MAC address macAdd (or every AllOne, if macAdd is "ALL"). Takes a hex
+// string. Returns an error if we can't send it (or, with Acknowledged(), if it isn't acknowledged). With "ALL",
+// the error is from the last AllOne that failed
+func (c *Client) EmitIR(IR string, macAdd string, opts ...CommandOption) error {
+	return c.emitIR(IR, macAdd, getCommandOptions(opts))
+}
+
+// emitIR does the work for EmitIR and the other IR senders
+func (c *Client) emitIR(IR string, macAdd string, o commandOptions) error {
+	if err := c.checkReady(); err != nil { // No connection, so nowhere to send it
+		return err
+	}
 
 	rnda := fmt.Sprintf("%02s", strconv.FormatInt(int64(rand.Intn(255)), 16)) // Gets a number between 0 and 255, makes it into a hex string, then pads it with zeros
 	rndb := fmt.Sprintf("%02s", strconv.FormatInt(int64(rand.Intn(255)), 16)) // Gets a number between 0 and 255, makes it into a hex string, then pads it with zeros
 	var irlen = fmt.Sprintf("%04s", strconv.FormatInt(int64(len(IR)/2), 16))
 	irlen = irlen[2:4] + irlen[0:2]
-	var packet = "6864" + "0000" + "6963" + "accfdeadbeef" + twenties + "65000000" + rnda + rndb + irlen + IR
-	var packetlen = fmt.Sprintf("%04s", strconv.FormatInt(int64(len(packet)/2), 16))
 
-	// 6864 irlen 6963 mac 202020202020 65 00 00 00 rnda rndb, len of IR, IR
+	// 6864 len 6963 mac 202020202020 65 00 00 00 rnda rndb, len of IR, IR
 	// this.hex2ba(hosts[index].macaddress), twenties, ['0x65', '0x00', '0x00', '0x00'], randomBitA, randomBitB, this.hex2ba(irLength), this.hex2ba(ir));
+	payload := "65000000" + rnda + rndb + irlen + IR
 	if macAdd == "ALL" {
-		for _, allones := range Devices {
+		var lastErr error
+		for _, allones := range c.deviceList() {
 			if allones.DeviceType == ALLONE {
-				packet = "6864" + packetlen + "6963" + allones.MACAddress + twenties + "65000000" + rnda + rndb + irlen + IR
+				msg, err := packet.NewPacket(packet.EmitIR, allones.MACAddress, payload)
+				if err == nil {
+					_, err = c.sendCommand("EmitIR", msg, allones, o, packet.EmitIR, nil) // The AllOne answers with an ic of its own
+				}
 
-				SendMessage(packet, allones)
+				if err != nil {
+					lastErr = err
+				}
 			}
 		}
-	} else {
-		if Devices[macAdd].DeviceType == ALLONE {
-			packet = "6864" + packetlen + "6963" + macAdd + twenties + "65000000" + rnda + rndb + irlen + IR
-			SendMessage(packet, Devices[macAdd])
-		}
+
+		return lastErr
+	}
+
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	if device.DeviceType != ALLONE {
+		return errors.New("Only AllOnes can emit IR")
 	}
+
+	msg, err := packet.NewPacket(packet.EmitIR, macAdd, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.sendCommand("EmitIR", msg, device, o, packet.EmitIR, nil)
+	return err
+}
+
+// EmitRF switches an RF switch on or off via the AllOne with the MAC address macAdd (or every AllOne, if macAdd
+// is "ALL"). code is the switch's code, as hex. If the switch has been paired with PairRFSwitch, SetRFState
+// looks the code up for you
+func (c *Client) EmitRF(state bool, code string, macAdd string, opts ...CommandOption) error {
+	return c.emitRF(state, code, macAdd, getCommandOptions(opts))
 }
 
-func EmitRF(state bool, RF string, macAdd string) {
+// emitRF does the work for EmitRF, and lets us know if it worked. With "ALL", the error is from the last AllOne that failed
+func (c *Client) emitRF(state bool, RF string, macAdd string, o commandOptions) error {
+	if err := c.checkReady(); err != nil { // No connection, so nowhere to send it
+		return err
+	}
+
+	if _, err := hex.DecodeString(RF); err != nil || RF == "" {
+		return fmt.Errorf("%q isn't an RF code. It should be a hex string, like 2b00daaeeb", RF)
+	}
 
 	var rfState string
 	if state == true {
@@ -229,55 +528,147 @@ func EmitRF(state bool, RF string, macAdd string) {
 
 	rnda := fmt.Sprintf("%02s", strconv.FormatInt(int64(rand.Intn(255)), 16)) // Gets a number between 0 and 255, makes it into a hex string, then pads it with zeros
 	rndb := fmt.Sprintf("%02s", strconv.FormatInt(int64(rand.Intn(255)), 16)) // Gets a number between 0 and 255, makes it into a hex string, then pads it with zeros
-	var packet = "6864" + "0000" + "6463" + "accfdeadbeef" + twenties + "3ef5ee0b" + rnda + rndb + rfState + RF
-	var packetlen = fmt.Sprintf("%04s", strconv.FormatInt(int64(len(packet)/2), 16))
-	fmt.Println("========", packet)
-	// 6864 irlen 6963 mac 202020202020 65 00 00 00 rnda rndb, len of IR, IR
-	// this.hex2ba(hosts[index].macaddress), twenties, ['0x65', '0x00', '0x00', '0x00'], randomBitA, randomBitB, this.hex2ba(irLength), this.hex2ba(ir));
+
+	// 6864 len 6463 mac 202020202020 3e f5 ee 0b rnda rndb, state, RF
+	payload := "3ef5ee0b" + rnda + rndb + rfState + RF
 	if macAdd == "ALL" {
-		for _, allones := range Devices {
+		var lastErr error
+		for _, allones := range c.deviceList() {
 			if allones.DeviceType == ALLONE {
-				packet = "6864" + packetlen + "6463" + allones.MACAddress + twenties + "3ef5ee0b" + rnda + rndb + rfState + RF
-				SendMessage(packet, allones)
+				msg, err := packet.NewPacket(packet.StateControl, allones.MACAddress, payload)
+				if err == nil {
+					_, err = c.sendCommand("EmitRF", msg, allones, o, packet.StateControl, nil) // The AllOne answers with a dc of its own
+				}
+
+				if err != nil {
+					lastErr = err
+				}
 			}
 		}
-	} else {
-		if Devices[macAdd].DeviceType == ALLONE {
-			packet = "6864" + packetlen + "6463" + macAdd + twenties + "3ef5ee0b" + rnda + rndb + rfState + RF
-			SendMessage(packet, Devices[macAdd])
-		}
+
+		return lastErr
+	}
+
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	if device.DeviceType != ALLONE {
+		return errors.New("Only AllOnes can emit RF")
+	}
+
+	msg, err := packet.NewPacket(packet.StateControl, macAdd, payload)
+	if err != nil {
+		return err
 	}
+
+	_, err = c.sendCommand("EmitRF", msg, device, o, packet.StateControl, nil)
+	return err
 }
 
-func EnterLearningMode(macAdd string) {
+// EnterLearningMode puts the AllOne into IR learning mode. Learning doesn't switch anything, so quiet windows don't apply.
+// With "ALL", every AllOne we know about is put into learning mode, and the error is from the last one that failed
+func (c *Client) EnterLearningMode(macAdd string) error {
+	if err := c.checkReady(); err != nil { // No connection, so nowhere to send it
+		return err
+	}
+
 	if macAdd == "ALL" {
-		for _, allones := range Devices {
+		var lastErr error
+		for _, allones := range c.deviceList() {
 			if allones.DeviceType == ALLONE {
-				SendMessage("686400186c73"+allones.MACAddress+twenties+"010000000000", allones)
-				passMessage("irlearnmode", allones)
+				if err := c.enterLearningMode(allones); err != nil {
+					lastErr = err
+				}
 			}
 		}
-	} else {
-		if Devices[macAdd].DeviceType == ALLONE {
-			SendMessage("686400186c73"+macAdd+twenties+"010000000000", Devices[macAdd])
-			passMessage("irlearnmode", Devices[macAdd])
-		}
+
+		return lastErr
 	}
+
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	if device.DeviceType != ALLONE {
+		return errors.New("Only AllOnes can learn IR")
+	}
+
+	return c.enterLearningMode(device)
 }
 
-func EnterRFLearningMode(macAdd string) {
-	SendMessage("646400187266"+macAdd+twenties+"010000000000", Devices[macAdd])
-	passMessage("rflearnmode", Devices[macAdd])
+// enterLearningMode does the work for EnterLearningMode, for one AllOne
+func (c *Client) enterLearningMode(device *Device) error {
+	msg, err := packet.NewPacket(packet.Learn, device.MACAddress, "010000000000")
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.sendControl("EnterLearningMode", msg, device, commandOptions{critical: true}); err != nil {
+		return err
+	}
+
+	c.passMessage("irlearnmode", c.snapshot(device))
+	return nil
+}
+
+// EnterRFLearningMode puts the AllOne into RF learning mode, so it can hear an RF switch or remote. Like
+// EnterLearningMode, quiet windows don't apply
+func (c *Client) EnterRFLearningMode(macAdd string) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	if device.DeviceType != ALLONE {
+		return errors.New("Only AllOnes can learn RF")
+	}
+
+	msg, err := packet.NewPacket(packet.RFLearn, macAdd, "010000000000")
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.sendControl("EnterRFLearningMode", msg, device, commandOptions{critical: true}); err != nil {
+		return err
+	}
+
+	c.passMessage("rflearnmode", c.snapshot(device))
+	return nil
 }
 
 // SendMessage is the heart of our library. Sends UDP messages to specified IP addresses
-func SendMessage(msg string, device *Device) (bool, error) {
+func (c *Client) SendMessage(msg string, device *Device) (bool, error) {
+
+	// No connection yet (or any more)? Nothing we can send
+	if err := c.checkReady(); err != nil {
+		return false, err
+	}
+
+	// In passive mode, we only listen
+	if c.Passive {
+		return false, errPassive
+	}
+
+	// If this device keeps failing, don't bother trying (broadcasts don't have a MAC, so they're never stopped)
+	c.devicesLock.Lock()
+	if device.MACAddress != "" {
+		if err := breakerAllows(device); err != nil {
+			c.devicesLock.Unlock()
+			return false, err
+		}
+	}
+
+	ip, ifIndex := device.IP, device.ifIndex // Where we're sending it. The listener can move the device while we're sending
+	c.devicesLock.Unlock()
 
 	// Turn this hex string into bytes for sending
 	buf, _ := hex.DecodeString(msg)
 
 	// Resolve our address, ready for sending data
-	udpAddr, resolveErr := net.ResolveUDPAddr("udp4", device.IP.String())
+	udpAddr, resolveErr := net.ResolveUDPAddr("udp4", ip.String())
 	if resolveErr != nil {
 		return false, resolveErr
 	}
@@ -285,13 +676,20 @@ func SendMessage(msg string, device *Device) (bool, error) {
 	// Actually write the data and send it off
 	// _ lets us ignore "declared but not used" errors. If we replace _ with n (number of bytes),
 	// We'd have to use n somewhere (e.g. fmt.Println(n, "bytes received")), but _ lets us ignore that
-	_, sendErr := conn.WriteToUDP(buf, udpAddr)
+	_, sendErr := c.writeToDevice(buf, udpAddr, ifIndex)
 	// If we've got an error
 	if sendErr != nil {
+		if device.MACAddress != "" {
+			c.recordFailure(device)
+		}
+		getLogger().Warn("Couldn't send to %s: %v", udpAddr.String(), sendErr)
 		return false, sendErr
 	}
 
-	passMessage("sendmessage", device)
+	traceFrame("out", msg, udpAddr)
+	logPacket("out", msg, udpAddr)
+	c.tapPacket(Outbound, buf, udpAddr)
+	c.passMessage("sendmessage", c.snapshot(device))
 	return true, nil
 }
 
@@ -299,175 +697,114 @@ func SendMessage(msg string, device *Device) (bool, error) {
 // Internal functions
 // ==================
 
-// handleMessage parses a message found by CheckForMessages
-func handleMessage(message string, addr *net.UDPAddr) (bool, error) {
+// handleMessage parses a message found by CheckForMessages. Anything we can't make sense of is raised as a "parseerror",
+// including anything that makes a handler panic, so one bad packet from something else on the network can't take us down
+func (c *Client) handleMessage(message string, addr *net.UDPAddr) (success bool, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			getLogger().Error("Panicked handling message from %s: %v\n%s", addr.String(), recovered, debug.Stack())
+			success, err = false, c.parseError(message, addr, fmt.Sprintf("Panicked while handling it: %v", recovered))
+		}
+	}()
 
 	if len(message) == 0 { // Blank message? Don't try and parse it!
-		return false, errors.New("Blank message")
+		return false, c.parseError(message, addr, "Blank message")
 	}
 
-	// If this is a broadcast message
-	if message == "686400067161" {
+	// If this is a broadcast message, answer for any virtual sockets we're pretending to be
+	if message == packet.DiscoverAll {
+		c.answerVirtualDiscovery(addr)
 		return true, nil
 	}
 
-	commandID := message[8:12]                  // What command we've received back
-	macStart := strings.Index(message, "accf")  // Find where our MAC Address starts
-	macAdd := message[macStart:(macStart + 12)] // The MAC address of the socket responding
-
-	switch commandID {
-	case "7161": // We've had a response to our broadcast message
-
-		_, exists := Devices[macAdd] // Check to see if we've already got macAdd in our array
-
-		if strings.Index(message, "49524430") > 0 { // Contains SOC0? It's a socket!
-			if exists == false { // We haven't got it in our Devices array?
-				deviceCount++ // Add one to the deviceCount
-				Devices[macAdd] = &Device{
-					ID:            deviceCount,
-					Name:          "", // No name yet
-					DeviceType:    ALLONE,
-					IP:            addr,
-					MACAddress:    macAdd,
-					Subscribed:    false,
-					Queried:       false,
-					State:         false,
-					RFSwitches:    make(map[string]RFSwitch), // Lightswitches
-					LastIRMessage: "",                        // The last IR message we've received
-					LastMessage:   message,                   // The last message we received
-				}
-
-				passMessage("allonefound", Devices[macAdd]) // Let our calling code know
-			} else {
-				Devices[macAdd].LastMessage = message // Set our LastMessage
-				passMessage("existingallonefound", Devices[macAdd])
-			}
-
-		} else if strings.Index(message, "534f4330") > 0 { // Contains IRD0? It's an IR blaster!
-			if exists == false { // If we don't have this device in our list already
-				deviceCount++ // Add one to the deviceCount
-				Devices[macAdd] = &Device{
-					ID:            deviceCount,
-					Name:          "",
-					DeviceType:    SOCKET,
-					IP:            addr,
-					MACAddress:    macAdd,
-					Subscribed:    false,
-					Queried:       false,
-					State:         false,
-					RFSwitches:    make(map[string]RFSwitch),
-					LastIRMessage: "",
-					LastMessage:   message,
-				}
+	// If it's a message for one of our virtual sockets, it's already been answered
+	if c.handleVirtualMessage(message, addr) {
+		return true, nil
+	}
 
-				lastBit := message[(len(message) - 1):] // Get the last bit from our message. 0 or 1 for off or on
-				if lastBit == "0" {
-					Devices[macAdd].State = false
-				} else {
-					Devices[macAdd].State = true
-				}
+	frame, err := packet.Parse(message) // Make sure it's a proper packet before we go reading bits out of it
+	if err != nil {
+		return false, c.parseError(message, addr, err.Error())
+	}
 
-				passMessage("socketfound", Devices[macAdd])
-			} else {
-				Devices[macAdd].LastMessage = message // Set our LastMessage
-				passMessage("existingsocketfound", Devices[macAdd])
-			}
-		} else {
-			Devices[macAdd].LastMessage = message // Set our LastMessage
-			passMessage("unknownhardwarefound", Devices[macAdd])
-		}
+	if frame.MAC == "" { // Nothing we can do with a message that isn't from a device
+		return true, nil
+	}
 
-	case "636c": // We've had confirmation of subscription
+	if c.ouiAllowed(frame.MAC) == false { // Not a device we've been told to deal with
+		return true, nil
+	}
 
-		// Sometimes we receive messages for sockets we don't know about. The WiWo
-		// app does this sometimes, as it sends messages to all AllOnes it knows about,
-		// regardless of whether or not they're active on the network. So we
-		// check to see if the socket that needs updating exists in our list. If it doesn't,
-		// we return false.
-		if exists(macAdd) == false {
-			return false, nil
-		}
+	commandID := frame.CommandID // What command we've received back
+	macAdd := frame.MAC          // The MAC address of the socket responding
 
-		lastBit := message[(len(message) - 1):] // Get the last bit from our message. 0 or 1 for off or on
-		if lastBit == "1" {
-			Devices[macAdd].State = true
-		} else {
-			Devices[macAdd].State = false
-		}
+	if device, found := c.device(macAdd); found { // Remember when we last heard from this device. If we can hear it, it's working
+		c.devicesLock.Lock()
+		device.LastSeen = time.Now()
+		back := device.Offline // It's back
+		device.Offline = false
+		c.devicesLock.Unlock()
 
-		Devices[macAdd].LastMessage = message // Set our LastMessage
-		passMessage("subscribed", Devices[macAdd])
+		c.recordSuccess(device)
 
-	case "6463": // Someone's pressed an RF switch.
-		var state bool
-		fmt.Println("Trying to parse the state of an RF switch. If this fails, please pass this info on to the developer!")
-		fmt.Println(message)
-		if message[48:50] == "00" {
-			state = false
-		} else {
-			state = true
+		if back {
+			c.passMessage("deviceonline", c.snapshot(device))
 		}
+	}
 
-		Devices[macAdd].RFSwitches[message[36:42]] = RFSwitch{State: state}
-		passMessage("rfswitch", Devices[macAdd])
+	defer c.resolveAcks(commandID, macAdd, message) // Once we've dealt with it, see if it's the answer to a command we sent
 
-	case "7274": // We've queried our socket, this is the data back
+	handler, found := handlers[commandID]
+	if found == false { // A command we don't know about. Nothing to do
+		return true, nil
+	}
 
-		// Our name starts after the fourth 202020202020, or 140 bytes in
-		strName := strings.TrimRight(message[140:172], "")
+	if success, err = handler(c, message, macAdd, addr); err != nil { // It had the right command ID, but not what should come with it
+		return success, c.parseError(message, addr, err.Error())
+	}
 
-		// And our name is 32 bytes long.
-		strDecName, _ := hex.DecodeString(strName[0:32])
+	return success, nil
+}
 
-		// If no name has been set, we get 32 bytes of F back, so
-		// we create a generic name so our socket name won't be spaces
-		if strName == "20202020202020202020202020202020" || strName == "ffffffffffffffffffffffffffffffff" {
-			if Devices[macAdd].DeviceType == SOCKET {
-				Devices[macAdd].Name = "Socket " + macAdd
-			} else {
-				Devices[macAdd].Name = "AllOne " + macAdd
-			}
+// parseError raises a "parseerror" for a message we couldn't handle, and returns reason as an error
+func (c *Client) parseError(message string, addr *net.UDPAddr, reason string) error {
+	c.passEvent("parseerror", &Device{IP: addr, LastMessage: message}, ParseErrorEvent{Message: message, From: addr.String(), Reason: reason})
+	return errors.New(reason)
+}
 
-		} else { // If a name WAS set
-			Devices[macAdd].Name = string(strDecName) // Convert back to text and assign
-		}
+// subscribeDevice asks a single device for control (subscription)
+func (c *Client) subscribeDevice(device *Device) (bool, error) {
+	// ReverseMAC takes a MAC address and reverses each pair (e.g. AC CF 23 becomes CA FC 32)
+	msg, err := packet.NewPacket(packet.Subscribe, device.MACAddress, packet.ReverseMAC(device.MACAddress)+twenties)
+	if err != nil {
+		return false, err
+	}
 
-		Devices[macAdd].LastMessage = message // Set our LastMessage
-		passMessage("queried", Devices[macAdd])
+	return c.SendMessage(msg, device)
+}
 
-	case "7366": // Confirmation of state change
+// queryDevice asks a single device for its details (table 4), which includes its name
+func (c *Client) queryDevice(device *Device) (bool, error) {
+	msg, err := packet.NewPacket(packet.ReadTable, device.MACAddress, tableQuery(4))
+	if err != nil {
+		return false, err
+	}
 
-		lastBit := message[(len(message) - 1):] // Get the last bit from our message. 0 or 1 for off or on
-		if lastBit == "0" {
-			Devices[macAdd].State = false
-		} else {
-			Devices[macAdd].State = true
-		}
+	return c.SendMessage(msg, device)
+}
 
-		Devices[macAdd].LastMessage = message // Set our LastMessage
-		passMessage("statechanged", Devices[macAdd])
-
-	case "6469": // We've pressed the button on the top of our AllOne
-		Devices[macAdd].LastMessage = message // Set our LastMessage
-		passMessage("buttonpress", Devices[macAdd])
-	case "6c73": // We've had an IR code back after learning mode
-		// 686400186c73accf232a5ffa202020202020000000000000
-		if len(message) >= 52 {
-			Devices[macAdd].LastIRMessage = message[52:]
-			Devices[macAdd].LastMessage = message // Set our LastMessage
-			passMessage("ircode", Devices[macAdd])
-		}
-	default: // No message? Return true
-		return true, nil
+// knownDevice finds a device we know about, for anything that takes a MAC address and needs our connection
+func (c *Client) knownDevice(macAdd string) (*Device, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
 	}
 
-	return true, nil
-}
+	device, found := c.device(macAdd)
+	if found == false {
+		return nil, fmt.Errorf("%s isn't a device we know about. Discover it first", macAdd)
+	}
 
-// Do we have macAdd in our Devices list?
-func exists(macAdd string) bool {
-	_, exists := Devices[macAdd]
-	return exists
+	return device, nil
 }
 
 // Gets our current IP address. This is used so we can ignore messages from ourselves
@@ -503,37 +840,84 @@ func getLocalIP() (string, error) {
 }
 
 // passMessage adds items to our Events channel so the calling code can be informed
-// It's non-blocking or whatever.
-func passMessage(message string, device *Device) bool {
+// It's non-blocking or whatever. device should be a copy (see snapshot) or one we've made up for the event,
+// never a live Device, since the calling code reads events on its own goroutine
+func (c *Client) passMessage(message string, device *Device) bool {
+	return c.passEvent(message, device, nil)
+}
+
+// passEvent is passMessage for events that carry a Payload
+func (c *Client) passEvent(message string, device *Device, payload interface{}) bool {
+
+	if CoalesceEvents && c.coalesce(message, device, payload) == false { // It's a repeat. It'll be passed on when the window closes
+		return true
+	}
+
+	c.sendEvent(EventStruct{Name: message, Type: eventType(message), DeviceInfo: device, Payload: payload, Count: 1})
+	return true
+}
+
+// sendEvent calls any callbacks registered for an event, then puts it on our Events channel and
+// the event streams of its device
+func (c *Client) sendEvent(event EventStruct) {
+	c.dispatch(event)
+	c.streamEvent(event)
+	c.queueEvent(event)
+}
+
+// queueEvent puts an event on our Events channel, without blocking. If the channel is full, the event is
+// dropped and counted. Once there's room again, an "eventsdropped" goes out first, saying how many were lost
+func (c *Client) queueEvent(event EventStruct) {
+	c.eventsLock.RLock()
+	defer c.eventsLock.RUnlock()
+
+	if c.eventsClosed { // Close has been called. Nobody's listening any more
+		return
+	}
+
+	if missed := atomic.SwapUint64(&c.unreportedDrops, 0); missed > 0 {
+		dropped := EventStruct{Name: "eventsdropped", Type: EventEventsDropped, DeviceInfo: &Device{}, Payload: EventsDroppedEvent{Dropped: missed, Total: c.DroppedEvents()}, Count: 1}
+		select {
+		case c.Events <- dropped:
+
+		default: // Still no room. We'll try again with the next event
+			atomic.AddUint64(&c.unreportedDrops, missed)
+		}
+	}
 
 	select {
-	case Events <- EventStruct{message, device}:
+	case c.Events <- event:
 
 	default:
+		atomic.AddUint64(&c.droppedEvents, 1)
+		atomic.AddUint64(&c.unreportedDrops, 1)
 	}
+}
 
-	return true
+// DroppedEvents returns how many events have been dropped because nobody was reading Events fast enough
+func (c *Client) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&c.droppedEvents)
 }
 
 // broadcastMessage is another core part of our code. It lets us broadcast a message to the whole network.
-// It's essentially SendMessage with a IPv4 Broadcast address
-func broadcastMessage(msg string) (bool, error) {
+// It's essentially SendMessage to the broadcast address of each of our subnets (see broadcastTargets)
+func (c *Client) broadcastMessage(msg string) (bool, error) {
+	var err error
+	sent := false
 
-	udpAddr, err := net.ResolveUDPAddr("udp4", net.IPv4bcast.String()+":10000")
-	SendMessage(msg, &Device{IP: udpAddr})
-	if err != nil {
-		return false, err
+	for _, target := range c.broadcastTargets() {
+		if _, sendErr := c.SendMessage(msg, &Device{IP: target.addr, ifIndex: target.ifIndex}); sendErr != nil {
+			err = sendErr // Keep trying the others. One interface being down shouldn't stop the rest
+			continue
+		}
+
+		sent = true
 	}
-	passMessage("broadcast", &Device{})
-	return true, nil
-}
 
-// Via http://stackoverflow.com/questions/19239449/how-do-i-reverse-an-array-in-go
-// Splits up a hex string into bytes then reverses the bytes
-func reverseMAC(mac string) string {
-	s, _ := hex.DecodeString(mac)
-	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
-		s[i], s[j] = s[j], s[i]
+	if sent == false {
+		return false, err
 	}
-	return hex.EncodeToString(s)
+
+	c.passMessage("broadcast", &Device{})
+	return true, nil
 }
@@ -0,0 +1,312 @@
+// Package orvibotest emulates Orvibo devices over UDP, so code that uses go-orvibo can be tested without an S20 or
+// an AllOne on the network. An Emulator listens on port 10000 of an address of your choosing, and answers discovery,
+// subscription, query, state and IR packets for every Device it's been given, the same way real hardware does.
+//
+// Devices are always talked to on port 10000, so the Emulator needs an address of its own, separate from the one
+// your Client listens on (go-orvibo ignores packets from its own address). On Linux, all of 127.0.0.0/8 is loopback,
+// so something like this works without any setup:
+//
+//	emulator, _ := orvibotest.NewEmulator("127.0.0.2", orvibotest.NewSocket("accf00000001", "Lamp"))
+//	defer emulator.Close()
+//
+//	client := orvibo.NewClient()
+//	client.Prepare(orvibo.WithBindAddr("127.0.0.1:10000"))
+//	client.AddDeviceByIP("127.0.0.2")
+//
+// On macOS and the BSDs, add 127.0.0.2 as an alias on lo0 first
+package orvibotest
+
+import (
+	"encoding/hex" // For building our replies
+	"fmt"          // For building our replies and error messages
+	"net"          // For listening
+	"strconv"      // For building our address
+	"strings"      // For padding
+	"sync"         // Devices are read and changed from the test and the emulator at the same time
+	"time"         // For our fake clock
+
+	"github.com/Grayda/go-orvibo/packet" // For command IDs and building our replies
+)
+
+// Port is the port devices listen on
+const Port = 10000
+
+// Models our devices report when they're discovered
+const (
+	SocketModel = "SOC002" // What an S20 reports
+	AllOneModel = "IRD014" // What an AllOne reports
+)
+
+// Device is one emulated device. Make them with NewSocket or NewAllOne, and hand them to NewEmulator
+type Device struct {
+	MACAddress string // 12 hex characters (e.g. accf00000001). Must be unique within the Emulator
+	Name       string // The name it reports when it's queried. Up to 16 bytes
+	Model      string // The model it reports when it's discovered. SocketModel or AllOneModel
+
+	state     bool         // Is it on or off? Only sockets have a state
+	learnCode string       // The IR code an AllOne hears when it's put into learning mode
+	emitted   []string     // IR codes an AllOne has been asked to emit, oldest first
+	received  []string     // Every packet sent to this device, oldest first
+	client    *net.UDPAddr // Who last subscribed, so we know who to tell about state changes
+}
+
+// NewSocket makes an emulated S20 socket, switched off
+func NewSocket(macAdd string, name string) *Device {
+	return &Device{MACAddress: strings.ToLower(macAdd), Name: name, Model: SocketModel}
+}
+
+// NewAllOne makes an emulated AllOne
+func NewAllOne(macAdd string, name string) *Device {
+	return &Device{MACAddress: strings.ToLower(macAdd), Name: name, Model: AllOneModel}
+}
+
+// Emulator answers packets on behalf of its Devices
+type Emulator struct {
+	conn    *net.UDPConn
+	devices map[string]*Device // Keyed by MAC address
+	lock    sync.Mutex         // Guards devices, and everything in them
+	done    chan bool          // Closed once our read loop has finished
+}
+
+// NewEmulator starts answering packets sent to port 10000 of ip, as each of devices
+func NewEmulator(ip string, devices ...*Device) (*Emulator, error) {
+	addr, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(ip, strconv.Itoa(Port)))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Emulator{devices: make(map[string]*Device), done: make(chan bool)}
+	for _, device := range devices {
+		if mac, err := hex.DecodeString(device.MACAddress); err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("%q isn't a valid MAC address. It should be 12 hex characters", device.MACAddress)
+		}
+
+		if _, found := e.devices[device.MACAddress]; found {
+			return nil, fmt.Errorf("There's already a device with MAC address %s", device.MACAddress)
+		}
+
+		if _, err := packet.EncodeName(device.Name); err != nil {
+			return nil, err
+		}
+
+		e.devices[device.MACAddress] = device
+	}
+
+	if e.conn, err = net.ListenUDP("udp4", addr); err != nil {
+		return nil, err
+	}
+
+	go e.serve()
+	return e, nil
+}
+
+// Addr is the address the Emulator is listening on
+func (e *Emulator) Addr() *net.UDPAddr {
+	return e.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// Close stops the Emulator and frees up its address
+func (e *Emulator) Close() error {
+	err := e.conn.Close()
+	<-e.done
+	return err
+}
+
+// State returns whether the socket with the MAC address macAdd is on
+func (e *Emulator) State(macAdd string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if device, found := e.devices[macAdd]; found {
+		return device.state
+	}
+
+	return false
+}
+
+// SetState switches the socket with the MAC address macAdd on or off, as if someone had pressed its button,
+// and tells whoever last subscribed to it
+func (e *Emulator) SetState(macAdd string, state bool) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	device, found := e.devices[macAdd]
+	if found == false {
+		return fmt.Errorf("There's no device with MAC address %s", macAdd)
+	}
+
+	device.state = state
+	if device.client == nil { // Nobody to tell
+		return nil
+	}
+
+	return e.reply(device.client, packet.StateChanged, device.MACAddress, "00000000"+device.stateBit())
+}
+
+// SetLearnCode sets the IR code the AllOne with the MAC address macAdd "hears" when it's next put into learning
+// mode. Leave it empty (the default) and the AllOne stays in learning mode without ever hearing anything
+func (e *Emulator) SetLearnCode(macAdd string, code string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if device, found := e.devices[macAdd]; found {
+		device.learnCode = code
+	}
+}
+
+// EmittedIR returns every IR code the AllOne with the MAC address macAdd has been asked to emit, oldest first
+func (e *Emulator) EmittedIR(macAdd string) []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if device, found := e.devices[macAdd]; found {
+		return append([]string(nil), device.emitted...)
+	}
+
+	return nil
+}
+
+// Received returns every packet (as hex) sent to the device with the MAC address macAdd, oldest first.
+// Discovery broadcasts aren't for any device in particular, so they aren't included
+func (e *Emulator) Received(macAdd string) []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if device, found := e.devices[macAdd]; found {
+		return append([]string(nil), device.received...)
+	}
+
+	return nil
+}
+
+// serve reads packets until Close is called
+func (e *Emulator) serve() {
+	defer close(e.done)
+
+	buf := make([]byte, 8192)
+	for {
+		n, addr, err := e.conn.ReadFromUDP(buf)
+		if err != nil { // Closed
+			return
+		}
+
+		e.handle(hex.EncodeToString(buf[:n]), addr)
+	}
+}
+
+// handle answers one packet
+func (e *Emulator) handle(message string, addr *net.UDPAddr) {
+	frame, err := packet.Parse(message)
+	if err != nil { // Not an Orvibo packet, so a real device would ignore it too
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if frame.CommandID == packet.Discover && frame.MAC == "" { // Everyone answers a discovery broadcast
+		for _, device := range e.devices {
+			e.send(addr, device.discoveryReply(packet.Discover))
+		}
+
+		return
+	}
+
+	device, found := e.devices[frame.MAC]
+	if found == false {
+		return
+	}
+
+	device.received = append(device.received, message)
+
+	switch frame.CommandID {
+	case packet.DiscoverMAC: // Someone's looking for this device specifically
+		e.send(addr, device.discoveryReply(packet.DiscoverMAC))
+	case packet.Subscribe: // We always say yes, and tell them our state
+		device.client = addr
+		e.reply(addr, packet.Subscribe, device.MACAddress, "0000000000"+device.stateBit())
+	case packet.ReadTable: // We only have table 4 (our details)
+		if len(message) >= 46 && message[44:46] == "04" {
+			e.send(addr, device.tableFour())
+		}
+	case packet.StateControl:
+		if device.Model != SocketModel || len(message) < 46 { // AllOnes get these for RF, which we don't emulate
+			return
+		}
+
+		device.state = message[len(message)-1:] != "0"
+		e.reply(addr, packet.StateChanged, device.MACAddress, "00000000"+device.stateBit())
+	case packet.EmitIR: // The code starts after the 65000000, two random bytes and the code's length
+		if device.Model != AllOneModel || len(message) < 52 {
+			return
+		}
+
+		device.emitted = append(device.emitted, message[52:])
+		e.reply(addr, packet.EmitIR, device.MACAddress, "000000000000")
+	case packet.Learn: // We're in learning mode. If we've got a code to hear, we "hear" it straight away
+		if device.Model != AllOneModel {
+			return
+		}
+
+		e.reply(addr, packet.Learn, device.MACAddress, "000000000000")
+		if device.learnCode != "" {
+			length := len(device.learnCode) / 2
+			e.reply(addr, packet.Learn, device.MACAddress, "000000000000"+fmt.Sprintf("%02x%02x", length&0xff, length>>8)+device.learnCode)
+		}
+	}
+}
+
+// reply builds a packet and sends it to addr
+func (e *Emulator) reply(addr *net.UDPAddr, commandID string, macAdd string, payload string) error {
+	msg, err := packet.NewPacket(commandID, macAdd, payload)
+	if err != nil {
+		return err
+	}
+
+	return e.send(addr, msg)
+}
+
+// send sends a packet (as hex) to addr
+func (e *Emulator) send(addr *net.UDPAddr, msg string) error {
+	buf, err := hex.DecodeString(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.conn.WriteToUDP(buf, addr)
+	return err
+}
+
+// stateBit is the device's state as the last byte of a packet
+func (device *Device) stateBit() string {
+	if device.state {
+		return "01"
+	}
+
+	return "00"
+}
+
+// discoveryReply builds the same reply a real device sends when it's discovered. commandID is 7161 or 7167,
+// depending on which kind of discovery we're replying to
+func (device *Device) discoveryReply(commandID string) string {
+	// Discovery replies have a status byte before the MAC address, so we put the MAC address in the payload ourselves
+	msg, _ := packet.NewPacket(commandID, "", "00"+device.MACAddress+packet.Padding+packet.ReverseMAC(device.MACAddress)+packet.Padding+
+		hex.EncodeToString([]byte(device.Model))+packet.EncodeClock(time.Now())+device.stateBit())
+	return msg
+}
+
+// tableFour builds a table 4 response (our details), laid out the same way a real S20 does it
+func (device *Device) tableFour() string {
+	name, _ := packet.EncodeName(device.Name) // Already checked in NewEmulator
+
+	msg, _ := packet.NewPacket(packet.ReadTable, device.MACAddress, "0200000000"+"0400"+"0100"+"00"+"8a00"+ // Status, table number and record length
+		"0100"+"4325"+device.MACAddress+packet.Padding+packet.ReverseMAC(device.MACAddress)+packet.Padding+ // Record ID, version, MAC addresses
+		hex.EncodeToString([]byte("888888      "))+name+"0400"+ // Remote password, name and icon
+		"20000000"+"1a000000"+"05000000"+ // Hardware, firmware and wifi firmware versions
+		"1027"+"00000000"+"1027"+strings.Repeat("20", 40)+ // Server port, server IP and port, and domain name (none)
+		"00000000"+"00000000"+"00000000"+ // Local IP, gateway and netmask (we use DHCP, so these are blank)
+		"01"+"01"+"00"+"00"+ // DHCP, discoverable, timezone set, timezone
+		"0000"+"0000") // Countdown status and countdown (no countdown)
+
+	return msg
+}
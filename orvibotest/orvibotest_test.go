@@ -0,0 +1,209 @@
+package orvibotest_test
+
+// Round trips between a real Client and the Emulator, so the Emulator can't quietly drift away from what the
+// Client sends and expects. Needs 127.0.0.2 (fine on Linux) and port 10000 on 127.0.0.1, or the tests are skipped
+
+import (
+	"context"       // For WaitForDevice
+	"encoding/hex"  // For reading raw packets
+	"net"           // For raw packet addresses
+	"path/filepath" // For somewhere to save devices
+	"strings"       // For building a long IR code
+	"sync"          // For the raw packets we've seen
+	"testing"
+	"time" // For timeouts
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/orvibotest"
+	"github.com/Grayda/go-orvibo/packet"
+)
+
+const (
+	socketMAC = "accf00000001"
+	allOneMAC = "accf00000002"
+)
+
+// roundTrip starts an Emulator with a socket and an AllOne on 127.0.0.2, and a listening Client on 127.0.0.1
+// that's found both of them
+func roundTrip(t *testing.T) (*orvibotest.Emulator, *orvibo.Client) {
+	emulator, err := orvibotest.NewEmulator("127.0.0.2", orvibotest.NewSocket(socketMAC, "Lamp"), orvibotest.NewAllOne(allOneMAC, "Lounge"))
+	if err != nil {
+		t.Skipf("Can't start the emulator on 127.0.0.2: %v", err)
+	}
+	t.Cleanup(func() { emulator.Close() })
+
+	c := orvibo.NewClient(orvibo.WithAutoSubscribe(true), orvibo.WithAutoQuery(true))
+	if _, err := c.Prepare(orvibo.WithBindAddr("127.0.0.1:10000")); err != nil {
+		t.Skipf("Can't listen on 127.0.0.1:10000: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if err := c.Listen(); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	if err := c.AddDeviceByIP("127.0.0.2"); err != nil {
+		t.Fatalf("AddDeviceByIP: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	for macAdd, name := range map[string]string{socketMAC: "Lamp", allOneMAC: "Lounge"} {
+		device, err := c.WaitForDevice(ctx, macAdd)
+		if err != nil {
+			t.Fatalf("WaitForDevice(%s): %v", macAdd, err)
+		}
+
+		if device.Name != name {
+			t.Errorf("%s is called %q, not %q", macAdd, device.Name, name)
+		}
+	}
+
+	return emulator, c
+}
+
+func TestEmulatorDiscovery(t *testing.T) {
+	_, c := roundTrip(t)
+
+	socket, _ := c.GetDevice(socketMAC)
+	if socket.DeviceType != orvibo.SOCKET || socket.Model != orvibotest.SocketModel || socket.State {
+		t.Errorf("Got %+v, not an S20 that's switched off", socket)
+	}
+
+	allOne, _ := c.GetDevice(allOneMAC)
+	if allOne.DeviceType != orvibo.ALLONE || allOne.Model != orvibotest.AllOneModel {
+		t.Errorf("Got %+v, not an AllOne", allOne)
+	}
+}
+
+func TestEmulatorState(t *testing.T) {
+	emulator, c := roundTrip(t)
+
+	for _, state := range []bool{true, false, true} { // The Client sends 00 or 01, and the Emulator reads the last character
+		if err := c.SetStateSync(socketMAC, state, time.Second); err != nil {
+			t.Fatalf("SetStateSync(%v): %v", state, err)
+		}
+
+		if emulator.State(socketMAC) != state {
+			t.Errorf("Set the socket to %v, but the emulator says it's %v", state, emulator.State(socketMAC))
+		}
+	}
+
+	if err := emulator.SetState(socketMAC, false); err != nil { // Someone pressed the button
+		t.Fatalf("SetState: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if device, _ := c.GetDevice(socketMAC); device.State == false {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("The Client never heard the socket switch off")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEmulatorIR(t *testing.T) {
+	emulator, c := roundTrip(t)
+
+	var lock sync.Mutex
+	var learnReplies []string
+	c.OnRawPacket(func(direction orvibo.Direction, data []byte, addr *net.UDPAddr) {
+		message := hex.EncodeToString(data)
+		if frame, err := packet.Parse(message); err == nil && direction == orvibo.Inbound && frame.CommandID == packet.Learn && len(message) > 52 {
+			lock.Lock()
+			learnReplies = append(learnReplies, message)
+			lock.Unlock()
+		}
+	})
+
+	code := strings.Repeat("0123456789", 60) // 300 bytes, so the length needs both of its bytes
+	emulator.SetLearnCode(allOneMAC, code)
+
+	learned, err := c.LearnIR(allOneMAC, time.Second)
+	if err != nil {
+		t.Fatalf("LearnIR: %v", err)
+	}
+
+	if learned != code {
+		t.Errorf("Learned %s, not %s", learned, code)
+	}
+
+	if err := c.EmitIR(learned, allOneMAC, orvibo.Acknowledged()); err != nil {
+		t.Fatalf("EmitIR: %v", err)
+	}
+
+	if emitted := emulator.EmittedIR(allOneMAC); len(emitted) != 1 || emitted[0] != code {
+		t.Errorf("The emulator was asked to emit %v, not %s", emitted, code)
+	}
+
+	// The length before the code is little endian (2c01 for 300) both ways. Check they agree
+	var sentLength string
+	for _, message := range emulator.Received(allOneMAC) {
+		if frame, err := packet.Parse(message); err == nil && frame.CommandID == packet.EmitIR {
+			sentLength = message[48:52]
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(learnReplies) != 1 || learnReplies[0][48:52] != "2c01" || sentLength != "2c01" {
+		t.Errorf("Learned %v and sent length %s. Both should have a length of 2c01", learnReplies, sentLength)
+	}
+}
+
+// TestConcurrentUse runs everything that changes devices in the background (the listener, auto discovery,
+// resubscription and the health monitor) while the test pokes at the same devices. It's for go test -race
+func TestConcurrentUse(t *testing.T) {
+	emulator, c := roundTrip(t)
+	emulator.SetLearnCode(allOneMAC, "aabbccdd")
+
+	c.StartAutoDiscovery(20 * time.Millisecond)
+	c.StartHealthMonitor(5 * time.Millisecond)
+
+	done := make(chan bool)
+	go func() { // Read events on another goroutine, the way the calling code would
+		defer close(done)
+		for event := range c.Events {
+			_ = event.DeviceInfo.State
+			_ = event.DeviceInfo.Name
+			_ = len(event.DeviceInfo.RFSwitches)
+			_ = event.DeviceInfo.LastMessage
+		}
+	}()
+
+	saved := filepath.Join(t.TempDir(), "devices.json")
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.ToggleState(socketMAC)
+		c.SetState(socketMAC, true)
+		c.Stats()
+		c.SaveDevices(saved)
+		c.LoadDevices(saved)
+		for _, device := range c.GetDevices() {
+			_ = device.State
+		}
+		c.Subscribe()
+		c.Query()
+		c.RegisterDevice(socketMAC, "127.0.0.2", orvibo.SOCKET)
+		c.SetCurtainCodes(allOneMAC, "Lounge", "aa", "bb", "cc")
+		c.OpenCurtain(allOneMAC, "Lounge")
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	if _, err := c.LearnIR(allOneMAC, time.Second); err != nil {
+		t.Errorf("LearnIR: %v", err)
+	}
+
+	if err := c.SetStateSync(socketMAC, false, time.Second); err != nil {
+		t.Errorf("SetStateSync: %v", err)
+	}
+
+	c.Close()
+	<-done
+}
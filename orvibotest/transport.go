@@ -0,0 +1,132 @@
+package orvibotest
+
+// A fake Transport, for tests that don't want to touch the network at all. Hand it to a Client with
+// orvibo.WithTransport, feed in packets with Deliver, and see what the Client sent with Sent
+
+import (
+	"encoding/hex" // Packets are handled as hex, like the rest of go-orvibo
+	"net"          // For addresses and errors
+	"os"           // For our timeout error
+	"sync"         // The Client reads and writes from different goroutines
+	"time"         // For read deadlines
+)
+
+// SentPacket is one packet a Client sent through a Transport
+type SentPacket struct {
+	Data string       // The packet, as hex
+	To   *net.UDPAddr // Where it was going
+}
+
+// inbound is a packet waiting to be read
+type inbound struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// Transport is an in-memory orvibo.Transport. Make one with NewTransport
+type Transport struct {
+	incoming chan inbound  // Packets fed in with Deliver, waiting for ReadFromUDP
+	closed   chan struct{} // Closed by Close
+
+	lock     sync.Mutex
+	sent     []SentPacket
+	deadline time.Time     // When ReadFromUDP gives up. Zero means never
+	wake     chan struct{} // Closed when the deadline changes, so a waiting ReadFromUDP can look at it again
+	isClosed bool
+}
+
+// NewTransport makes a Transport with nothing waiting to be read
+func NewTransport() *Transport {
+	return &Transport{incoming: make(chan inbound, 64), closed: make(chan struct{}), wake: make(chan struct{})}
+}
+
+// Deliver queues a packet (as hex) for the Client to read, as if it had come from from
+func (t *Transport) Deliver(message string, from *net.UDPAddr) error {
+	data, err := hex.DecodeString(message)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case t.incoming <- inbound{data: data, from: from}:
+		return nil
+	case <-t.closed:
+		return net.ErrClosed
+	}
+}
+
+// Sent returns every packet the Client has sent, oldest first
+func (t *Transport) Sent() []SentPacket {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return append([]SentPacket(nil), t.sent...)
+}
+
+// ReadFromUDP returns the next packet fed in with Deliver, waiting for one if need be
+func (t *Transport) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	for {
+		t.lock.Lock()
+		deadline, wake := t.deadline, t.wake
+		t.lock.Unlock()
+
+		var timeout <-chan time.Time
+		if deadline.IsZero() == false {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return 0, nil, os.ErrDeadlineExceeded
+			}
+
+			timer := time.NewTimer(wait)
+			timeout = timer.C
+			defer timer.Stop() // We only go around again when the deadline changes, so these don't pile up
+		}
+
+		select {
+		case packet := <-t.incoming:
+			return copy(b, packet.data), packet.from, nil
+		case <-timeout:
+			return 0, nil, os.ErrDeadlineExceeded
+		case <-wake: // The deadline's changed, so go around again
+		case <-t.closed:
+			return 0, nil, net.ErrClosed
+		}
+	}
+}
+
+// WriteToUDP records a packet, for Sent
+func (t *Transport) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.isClosed {
+		return 0, net.ErrClosed
+	}
+
+	t.sent = append(t.sent, SentPacket{Data: hex.EncodeToString(b), To: addr})
+	return len(b), nil
+}
+
+// SetReadDeadline makes ReadFromUDP give up at deadline, including one that's already waiting
+func (t *Transport) SetReadDeadline(deadline time.Time) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.deadline = deadline
+	close(t.wake)
+	t.wake = make(chan struct{})
+	return nil
+}
+
+// Close stops the Transport. Anything waiting in ReadFromUDP returns straight away
+func (t *Transport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.isClosed == false {
+		t.isClosed = true
+		close(t.closed)
+	}
+
+	return nil
+}
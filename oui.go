@@ -0,0 +1,33 @@
+package orvibo
+
+// Orvibo devices used to all have MAC addresses starting with AC:CF, but newer hardware ships with other
+// prefixes. We read the MAC address from its fixed spot in each packet, so any device will do, but if you'd
+// rather only talk to certain manufacturers' devices, you can limit it by OUI (the first three bytes of the MAC address)
+
+import (
+	"strings" // For tidying up OUIs
+)
+
+// AllowedOUIs, if set, limits which devices we'll deal with to the ones whose MAC addresses start with one of these
+// OUIs, e.g. []string{"AC:CF:23", "b4430d"}. Messages from any other device are ignored. Leave it empty to accept every device
+var AllowedOUIs []string
+
+// normaliseOUI turns AC:CF:23 or ac-cf-23 into accf23, which is how MAC addresses appear in our packets
+func normaliseOUI(oui string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", "-", "", " ", "").Replace(oui))
+}
+
+// ouiAllowed checks if a MAC address starts with one of our AllowedOUIs. If there aren't any, every MAC address is fine
+func (c *Client) ouiAllowed(mac string) bool {
+	if len(c.AllowedOUIs) == 0 {
+		return true
+	}
+
+	for _, oui := range c.AllowedOUIs {
+		if strings.HasPrefix(mac, normaliseOUI(oui)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package orvibo_test
+
+import (
+	"testing"
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/orvibotest"
+)
+
+func TestAllowedOUIs(t *testing.T) {
+	transport := orvibotest.NewTransport()
+	c := orvibo.NewClient(orvibo.WithTransport(transport), orvibo.WithAllowedOUIs("B4:43:0D"))
+	if _, err := c.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer c.Close()
+
+	// An AC:CF socket isn't on the list, so it's ignored altogether
+	transport.Deliver(discoveryReply(t, testSocket, "SOC002", "01"), testAddr)
+	if _, err := c.CheckForMessages(); err != nil {
+		t.Fatalf("CheckForMessages: %v", err)
+	}
+
+	if _, found := c.GetDevice(testSocket); found {
+		t.Errorf("Found %s, even though its OUI isn't allowed", testSocket)
+	}
+
+	for len(c.Events) > 0 {
+		if event := <-c.Events; event.DeviceInfo.MACAddress == testSocket {
+			t.Errorf("Got %q for %s, even though its OUI isn't allowed", event.Name, testSocket)
+		}
+	}
+
+	// One that is gets found as usual
+	allowed := "b4430d000001"
+	transport.Deliver(discoveryReply(t, allowed, "SOC002", "01"), testAddr)
+	c.CheckForMessages()
+
+	if found := expectEvent(t, c, "socketfound"); found.DeviceInfo.MACAddress != allowed {
+		t.Errorf("socketfound is for %s, not %s", found.DeviceInfo.MACAddress, allowed)
+	}
+}
@@ -0,0 +1,52 @@
+package packet
+
+// Building packets. Every packet is the magic word, a two byte (big-endian) length that counts the whole
+// packet, the command ID, then (usually) the MAC address of the device it's for and some padding, then the payload
+
+import (
+	"encoding/hex" // For checking our hex
+	"fmt"          // For building our length and error messages
+)
+
+// NewPacket builds a packet for the device with the given MAC address, working out the length for us.
+// commandID, mac and payload are all hex. Leave mac blank for packets that don't have one (e.g. discovery broadcasts)
+func NewPacket(commandID string, mac string, payload string) (string, error) {
+	if err := checkHex("command ID", commandID, 2); err != nil {
+		return "", err
+	}
+
+	body := commandID
+	if mac != "" {
+		if err := checkHex("MAC address", mac, 6); err != nil {
+			return "", err
+		}
+
+		body += mac + Padding
+	}
+
+	if err := checkHex("payload", payload, -1); err != nil {
+		return "", err
+	}
+
+	body += payload
+	length := len(body)/2 + 4 // The magic word and the length itself count too
+	if length > 0xffff {
+		return "", fmt.Errorf("Packet is %d bytes long, but the most we can send is %d", length, 0xffff)
+	}
+
+	return MagicWord + fmt.Sprintf("%04x", length) + body, nil
+}
+
+// checkHex makes sure s is valid hex, and if size isn't -1, that it's size bytes long
+func checkHex(what string, s string, size int) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%s %q isn't valid hex: %v", what, s, err)
+	}
+
+	if size >= 0 && len(b) != size {
+		return fmt.Errorf("%s %q should be %d bytes, but it's %d", what, s, size, len(b))
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package packet
+
+import (
+	"strings" // For building long names and payloads
+	"testing"
+)
+
+const testMAC = "accf23aabbcc"
+
+func TestNewPacket(t *testing.T) {
+	msg, err := NewPacket(Subscribe, testMAC, ReverseMAC(testMAC)+Padding)
+	if err != nil {
+		t.Fatalf("NewPacket: %v", err)
+	}
+
+	want := "6864001e636c" + testMAC + Padding + "ccbbaa23cfac" + Padding // The MAC address again, backwards a byte at a time
+	if msg != want {
+		t.Errorf("Got %s, not %s", msg, want)
+	}
+
+	if msg, _ := NewPacket(Discover, "", ""); msg != DiscoverAll { // No MAC address, no padding
+		t.Errorf("Got %s, not %s", msg, DiscoverAll)
+	}
+
+	for _, bad := range []struct{ commandID, mac, payload string }{
+		{"636", testMAC, ""},                              // Half a command ID
+		{Subscribe, "accf23", ""},                         // Short MAC address
+		{Subscribe, "zzcf23aabbcc", ""},                   // Not hex
+		{Subscribe, testMAC, "0"},                         // Odd length payload
+		{Subscribe, testMAC, strings.Repeat("00", 65536)}, // Too long for the length field
+	} {
+		if _, err := NewPacket(bad.commandID, bad.mac, bad.payload); err == nil {
+			t.Errorf("NewPacket(%q, %q, %d hex characters) should have failed", bad.commandID, bad.mac, len(bad.payload))
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package packet
+
+// Parsing packets. Parse checks a packet is well formed before anything tries to read fields out of it,
+// so a short or garbled datagram gets an error back rather than causing a panic
+
+import (
+	"encoding/hex" // For checking our hex
+	"errors"       // For crafting our own errors
+)
+
+// The ways a packet can fail to parse
+var (
+	ErrTooShort  = errors.New("Packet is too short to be an Orvibo packet")
+	ErrNotHex    = errors.New("Packet isn't valid hex")
+	ErrMagicWord = errors.New("Packet doesn't start with the magic word (6864)")
+	ErrLength    = errors.New("Packet length doesn't match the length in its header")
+)
+
+// Frame is a packet that's been checked and split into its parts
+type Frame struct {
+	Length    int    // The length from the header, in bytes
+	CommandID string // The command ID (e.g. 7161)
+	MAC       string // The MAC address of the device the packet is from or for. Blank if the packet doesn't have one
+	Payload   string // Everything after the MAC address and its padding (or after the command ID, if there's no MAC address)
+	Raw       string // The whole packet
+}
+
+// Parse checks that a packet (as hex) is well formed, and splits it into a Frame. The MAC address is read from
+// its fixed spot, 6 bytes in, except in discovery replies, where there's a status byte before it
+func Parse(message string) (*Frame, error) {
+	if len(message) < 12 { // Magic word, length and command ID
+		return nil, ErrTooShort
+	}
+
+	if _, err := hex.DecodeString(message); err != nil {
+		return nil, ErrNotHex
+	}
+
+	if message[0:4] != MagicWord {
+		return nil, ErrMagicWord
+	}
+
+	length, _ := Length(message)
+	if length*2 != len(message) {
+		return nil, ErrLength
+	}
+
+	frame := &Frame{Length: length, CommandID: message[8:12], Payload: message[12:], Raw: message}
+
+	macStart := 12
+	if (frame.CommandID == Discover || frame.CommandID == DiscoverMAC) && len(message) >= 84 { // A discovery reply
+		macStart = 14
+	}
+
+	if len(message) >= macStart+24 { // Room for a MAC address and its padding
+		frame.MAC = message[macStart : macStart+12]
+		frame.Payload = message[macStart+24:]
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,66 @@
+package packet
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	msg, _ := NewPacket(StateChanged, testMAC, "0000000001")
+	frame, err := Parse(msg)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if frame.Length != len(msg)/2 || frame.CommandID != StateChanged || frame.MAC != testMAC || frame.Payload != "0000000001" || frame.Raw != msg {
+		t.Errorf("Got %+v from %s", frame, msg)
+	}
+
+	// Discovery replies have a status byte before the MAC address
+	reply, _ := NewPacket(Discover, "", "00"+testMAC+Padding+ReverseMAC(testMAC)+Padding+"534f43303032"+EncodeClock(Epoch)+"01")
+	if frame, err := Parse(reply); err != nil || frame.MAC != testMAC {
+		t.Errorf("Got %+v, %v from a discovery reply for %s", frame, err, testMAC)
+	}
+
+	if frame, err := Parse(DiscoverAll); err != nil || frame.MAC != "" || frame.CommandID != Discover { // No room for a MAC address
+		t.Errorf("Got %+v, %v from a discovery broadcast", frame, err)
+	}
+
+	for message, want := range map[string]error{
+		"6864":                ErrTooShort,
+		"6864000671zz":        ErrNotHex,
+		"686500067161":        ErrMagicWord,
+		"686400077161":        ErrLength,
+		DiscoverAll + "00000": ErrNotHex, // Half a byte on the end
+		DiscoverAll + "0000":  ErrLength,
+	} {
+		if _, err := Parse(message); err != want {
+			t.Errorf("Parse(%s) returned %v, not %v", message, err, want)
+		}
+	}
+}
+
+// FuzzParse feeds Parse garbage. It shouldn't panic, and anything it accepts should be what it says it is
+func FuzzParse(f *testing.F) {
+	msg, _ := NewPacket(StateChanged, testMAC, "0000000001")
+	for _, seed := range []string{
+		"", "6864", "68640006", "686400067161", DiscoverAll + "0", msg, msg[:len(msg)-2], msg + "00", "6864zzzz7161",
+		"686400ff7161", "6864000c7161accf23aabb", "6865" + msg[4:],
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, message string) {
+		frame, err := Parse(message)
+		if err != nil {
+			return
+		}
+
+		if frame.Raw != message || frame.Length*2 != len(message) || len(frame.CommandID) != 4 {
+			t.Errorf("Parse(%q) accepted it as %+v", message, frame)
+		}
+
+		if frame.MAC != "" && len(frame.MAC) != 12 {
+			t.Errorf("Parse(%q) gave a MAC address of %q", message, frame.MAC)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package packet
+
+// Device names live in a fixed 16 byte field in table 4, padded out with spaces
+
+import (
+	"encoding/hex" // For turning our name into hex
+	"fmt"          // For building our error messages
+	"strings"      // For padding
+	"unicode/utf8" // For checking our name is valid text
+)
+
+// NameLength is how many bytes a device name can be. Non-English characters take up more than one byte each
+const NameLength = 16
+
+// ValidateName checks that a name will fit in the name field. Returns a descriptive error if it won't
+func ValidateName(name string) error {
+	if utf8.ValidString(name) == false {
+		return fmt.Errorf("Name %q isn't valid UTF-8 text", name)
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("Name can't be blank")
+	}
+
+	if len(name) > NameLength {
+		return fmt.Errorf("Name %q is %d bytes long, but devices only have room for %d. Try %q", name, len(name), NameLength, TruncateName(name))
+	}
+
+	return nil
+}
+
+// EncodeName validates a name, then turns it into the 16 bytes (as hex) that go in the name field, padded with spaces
+func EncodeName(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString([]byte(name + strings.Repeat(" ", NameLength-len(name)))), nil
+}
+
+// DecodeName turns the name field back into text, trimming off the padding
+func DecodeName(field []byte) string {
+	return strings.TrimRight(string(field), " \x00\xff")
+}
+
+// TruncateName cuts a name down to NameLength bytes, without cutting a character in half
+func TruncateName(name string) string {
+	if len(name) <= NameLength {
+		return name
+	}
+
+	cut := NameLength
+	for cut > 0 && utf8.RuneStart(name[cut]) == false { // Step back to the start of the character we landed in
+		cut--
+	}
+
+	return name[:cut]
+}
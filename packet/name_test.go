@@ -0,0 +1,31 @@
+package packet
+
+import (
+	"strings" // For building long names
+	"testing"
+)
+
+func TestEncodeName(t *testing.T) {
+	encoded, err := EncodeName("Lamp")
+	if err != nil {
+		t.Fatalf("EncodeName: %v", err)
+	}
+
+	if encoded != "4c616d70"+strings.Repeat("20", 12) { // Padded out to 16 bytes with spaces
+		t.Errorf("Got %s", encoded)
+	}
+
+	for _, bad := range []string{"", "   ", strings.Repeat("a", NameLength+1), "\xff\xfe"} {
+		if _, err := EncodeName(bad); err == nil {
+			t.Errorf("EncodeName(%q) should have failed", bad)
+		}
+	}
+
+	if name := DecodeName([]byte("Lamp            ")); name != "Lamp" {
+		t.Errorf("DecodeName gave %q, not Lamp", name)
+	}
+
+	if name := TruncateName("Wohnzimmerlampeü"); name != "Wohnzimmerlampe" { // ü is two bytes, so it won't fit
+		t.Errorf("TruncateName gave %q", name)
+	}
+}
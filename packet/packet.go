@@ -0,0 +1,59 @@
+// Package packet is the Orvibo wire format on its own: command IDs, framing and parsers, with no networking
+// or global state. go-orvibo uses it to talk to devices, but it's just as useful for capture analysers,
+// emulators or anything else that needs to read or write Orvibo packets.
+//
+// Like the rest of go-orvibo, packets are handled as hex strings (e.g. "686400067161")
+package packet
+
+import (
+	"encoding/hex" // For reversing MAC addresses
+)
+
+// MagicWord is how every Orvibo packet starts. It's "hd" in ASCII
+const MagicWord = "6864"
+
+// Padding follows every MAC address in a packet. It's six spaces
+const Padding = "202020202020"
+
+// Command IDs. These are the two ASCII characters after the length (e.g. "qa" is 7161). See protocol.txt for more
+const (
+	CountdownControl = "6364" // cd - Start or stop a countdown
+	Subscribe        = "636c" // cl - Subscribe (A.K.A TCP Login Command), and its response
+	ClockSync        = "6373" // cs - Clock synchronization
+	StateControl     = "6463" // dc - Turn a socket on or off, or an RF switch via the AllOne
+	ButtonPress      = "6469" // di - Button on the AllOne pressed
+	Heartbeat        = "6862" // hb - "Heartbeet" (periodic ping)
+	EmitIR           = "6963" // ic - Emit an IR code from the AllOne
+	Learn            = "6c73" // ls - Enter IR learning mode, and the learned code that comes back
+	ModifyPassword   = "6d70" // mp - Modify remote password
+	Discover         = "7161" // qa - Search for devices where the MAC is unknown
+	DiscoverMAC      = "7167" // qg - Search for a device where the MAC is known
+	RFLearn          = "7266" // rf - Enter RF learning mode on the AllOne
+	ReadTable        = "7274" // rt - Read a table, and the data that comes back
+	StateChanged     = "7366" // sf - A socket's state has changed (e.g. via its button, or confirming a dc)
+	ModifyTable      = "746d" // tm - Change a table read by ReadTable
+)
+
+// DiscoverAll is the discovery (qa) broadcast that finds every device on the network
+const DiscoverAll = MagicWord + "0006" + Discover
+
+// ReverseMAC reverses the bytes in a MAC address (e.g. accf23 becomes 23cfac). Subscription packets need it.
+// Via http://stackoverflow.com/questions/19239449/how-do-i-reverse-an-array-in-go
+func ReverseMAC(mac string) string {
+	s, _ := hex.DecodeString(mac)
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+
+	return hex.EncodeToString(s)
+}
+
+// CommandName turns a command ID into the two letter name the protocol documentation uses (e.g. 7161 becomes "qa")
+func CommandName(commandID string) string {
+	name, err := hex.DecodeString(commandID)
+	if err != nil || len(name) != 2 {
+		return ""
+	}
+
+	return string(name)
+}
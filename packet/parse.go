@@ -0,0 +1,134 @@
+package packet
+
+// Parsers for the fields we know how to read out of a packet. Offsets are in hex characters, so byte n is at 2n
+
+import (
+	"encoding/hex" // For converting to and from hex
+	"strings"      // For trimming padding
+	"time"         // For clocks and countdowns
+)
+
+// Epoch is where device clocks count from: midnight, January 1st 1900
+var Epoch = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// CommandID returns which command a packet is (e.g. Discover or StateChanged)
+func CommandID(message string) (string, bool) {
+	if len(message) < 12 {
+		return "", false
+	}
+
+	return message[8:12], true
+}
+
+// Length returns the packet length from the header (the two bytes after the magic word)
+func Length(message string) (int, bool) {
+	if len(message) < 8 || message[0:4] != MagicWord {
+		return 0, false
+	}
+
+	b, err := hex.DecodeString(message[4:8])
+	if err != nil {
+		return 0, false
+	}
+
+	return int(b[0])<<8 | int(b[1]), true
+}
+
+// State reads the on/off state from the last byte of a packet. Discovery replies, subscription
+// responses, state changes and state commands all put it there
+func State(message string) bool {
+	return strings.HasSuffix(message, "0") == false
+}
+
+// Model pulls the six character model string (e.g. SOC002 or IRD014) out of a discovery reply. It comes straight
+// after the reversed MAC address and its padding, 31 bytes in
+func Model(message string) (string, bool) {
+	if len(message) < 74 {
+		return "", false
+	}
+
+	model, err := hex.DecodeString(message[62:74])
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(string(model)), true
+}
+
+// Clock reads the device's clock out of a discovery reply. After the model comes four bytes
+// of "seconds since 1900" in little-endian order, then the state byte
+func Clock(message string) (time.Time, bool) {
+	if len(message) < 84 { // A full reply is 42 bytes. Anything shorter doesn't have a clock in it
+		return time.Time{}, false
+	}
+
+	b, err := hex.DecodeString(message[74:82])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	seconds := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+	return Epoch.Add(time.Duration(seconds) * time.Second), true
+}
+
+// EncodeClock turns a time into the four clock bytes of a discovery reply
+func EncodeClock(t time.Time) string {
+	seconds := uint32(t.Sub(Epoch) / time.Second)
+	return hex.EncodeToString([]byte{byte(seconds), byte(seconds >> 8), byte(seconds >> 16), byte(seconds >> 24)})
+}
+
+// Name reads the device name out of a table 4 response. It's 16 bytes, 70 bytes in, padded with spaces.
+// Returns false if no name has been set (all spaces or all 0xFF)
+func Name(message string) (string, bool) {
+	if len(message) < 172 {
+		return "", false
+	}
+
+	field := message[140:172]
+	if field == strings.Repeat("20", NameLength) || field == strings.Repeat("ff", NameLength) {
+		return "", false
+	}
+
+	b, err := hex.DecodeString(field)
+	if err != nil {
+		return "", false
+	}
+
+	return DecodeName(b), true
+}
+
+// Countdown reads the auto-off countdown out of a table 4 response. The status is two bytes at 164, and is
+// zero when no countdown is running. The countdown itself is two little-endian bytes of seconds at 166.
+// Returns false if the response is too short to have a countdown in it
+func Countdown(message string) (time.Duration, bool) {
+	if len(message) < 336 { // The full record is 168 bytes. Older firmware may not send the countdown at all
+		return 0, false
+	}
+
+	b, err := hex.DecodeString(message[328:336])
+	if err != nil {
+		return 0, false
+	}
+
+	status := uint16(b[0]) | uint16(b[1])<<8
+	seconds := uint16(b[2]) | uint16(b[3])<<8
+	if status == 0 || seconds == 0xffff {
+		return 0, true
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// Table reads which table a read table (rt) response is for. It's the byte after the five byte status, 23 bytes in
+func Table(message string) (int, bool) {
+	if len(message) < 48 {
+		return 0, false
+	}
+
+	b, err := hex.DecodeString(message[46:48])
+	if err != nil {
+		return 0, false
+	}
+
+	return int(b[0]), true
+}
@@ -0,0 +1,33 @@
+package orvibo
+
+// Passive (monitor) mode. We never transmit anything, we just listen for what other controllers (the WiWo app,
+// another bridge etc.) are telling our devices, and keep our Devices list in sync. Great for read-only
+// dashboards, or for debugging setups with more than one controller
+
+import (
+	"errors" // For crafting our own errors
+	"net"    // For checking where a message came from
+)
+
+// Passive turns on passive mode. While it's on, SendMessage (and everything that uses it) refuses to send
+var Passive = false
+
+// errPassive is what SendMessage returns in passive mode
+var errPassive = errors.New("Passive mode is on, so nothing is sent")
+
+// handleSocketCommand deals with a state change command (dc) for one of our sockets that didn't come from us.
+// It's another controller telling the socket what to do, so we update our State to match. The socket will
+// confirm it with a "statechanged" if we're subscribed
+func (c *Client) handleSocketCommand(device *Device, message string, addr *net.UDPAddr) {
+	c.devicesLock.Lock()
+	if device.IP != nil && addr.IP.Equal(device.IP.IP) { // It's from the socket itself, not another controller
+		c.devicesLock.Unlock()
+		return
+	}
+
+	device.State = message[(len(message)-1):] != "0" // Last bit is 0 or 1 for off or on
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("externalstateset", snapshot)
+}
@@ -0,0 +1,166 @@
+package orvibo
+
+// PCAP replay. A capture of Orvibo traffic (from tcpdump or Wireshark) can be fed back through the message
+// handler, raising the same events it would have live. So someone with a device we don't support yet can send
+// in a capture, and we can see exactly what the library makes of it without having the hardware.
+//
+// Only the classic pcap format is read, not pcapng. Wireshark can save as either ("Wireshark/tcpdump - pcap"),
+// and tcpdump -w writes pcap. IPv4 over Ethernet, Linux cooked (tcpdump -i any), loopback and raw IP captures work
+
+import (
+	"bufio"           // For reading the capture
+	"encoding/binary" // For reading headers
+	"errors"          // For crafting our own errors
+	"fmt"             // For building our error messages
+	"io"              // For reading the capture
+	"net"             // For addresses
+	"os"              // For opening the capture
+)
+
+// pcap link types we know how to get an IPv4 packet out of
+const (
+	linkNull     = 0   // BSD loopback. 4 bytes of address family first
+	linkEthernet = 1   // Ethernet. 14 byte header, maybe with VLAN tags
+	linkRaw      = 101 // Raw IP, no header
+	linkLinuxSLL = 113 // Linux cooked capture (tcpdump -i any). 16 byte header
+	linkIPv4     = 228 // Raw IPv4, no header
+	linkLinuxSL2 = 276 // Linux cooked capture v2. 20 byte header
+)
+
+// ReplayPCAP reads a pcap capture and passes every UDP packet to or from port 10000 through the message handler, as if
+// we'd just received it, raising the same events we would have live. It returns how many packets it replayed.
+//
+// Packets are replayed as fast as they can be read, not at their original pace. Anything the handlers would normally
+// send back (subscribing to new devices, say) goes out for real if we're prepared, so replay on a Client that isn't
+// prepared, or is Passive, unless that's what you want. Don't replay while Listen is running
+func (c *Client) ReplayPCAP(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	replayed := 0
+	err = readPCAP(bufio.NewReader(f), func(from *net.UDPAddr, to *net.UDPAddr, payload []byte) {
+		if from.Port != devicePort && to.Port != devicePort { // Not Orvibo traffic
+			return
+		}
+
+		c.receive(payload, from)
+		replayed++
+	})
+
+	return replayed, err
+}
+
+// readPCAP reads a pcap capture, and calls each for every IPv4 UDP packet in it. Packets we can't read (other
+// protocols, IP fragments and so on) are skipped
+func readPCAP(r io.Reader, each func(from *net.UDPAddr, to *net.UDPAddr, payload []byte)) error {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("Couldn't read the pcap header: %v", err)
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(header) {
+	case 0xa1b2c3d4, 0xa1b23c4d: // Microsecond and nanosecond timestamps, written little endian
+		order = binary.LittleEndian
+	case 0xd4c3b2a1, 0x4d3cb2a1: // The same, big endian
+		order = binary.BigEndian
+	case 0x0a0d0d0a:
+		return errors.New("This is a pcapng capture. Save it as pcap (\"Wireshark/tcpdump - pcap\") and try again")
+	default:
+		return errors.New("This isn't a pcap capture")
+	}
+
+	linkType := order.Uint32(header[20:]) & 0xffff // The top bits can hold FCS details, which we don't need
+
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("Couldn't read a pcap record: %v", err)
+		}
+
+		frame := make([]byte, order.Uint32(record[8:]))
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return fmt.Errorf("Couldn't read a pcap record: %v", err)
+		}
+
+		if from, to, payload, ok := udpFromFrame(linkType, frame); ok {
+			each(from, to, payload)
+		}
+	}
+}
+
+// udpFromFrame digs the UDP payload out of a captured frame, along with where it came from and went to
+func udpFromFrame(linkType uint32, frame []byte) (*net.UDPAddr, *net.UDPAddr, []byte, bool) {
+	ip, ok := ipFromFrame(linkType, frame)
+	if ok == false || len(ip) < 20 || ip[0]>>4 != 4 || ip[9] != 17 { // Not IPv4, or not UDP
+		return nil, nil, nil, false
+	}
+
+	headerLength := int(ip[0]&0x0f) * 4
+	totalLength := int(binary.BigEndian.Uint16(ip[2:]))
+	if binary.BigEndian.Uint16(ip[6:])&0x3fff != 0 { // A fragment. Orvibo packets are small enough that this shouldn't happen
+		return nil, nil, nil, false
+	}
+
+	if totalLength < len(ip) { // Ethernet pads short frames out, so go by the IP header's length
+		ip = ip[:totalLength]
+	}
+
+	if len(ip) < headerLength+8 {
+		return nil, nil, nil, false
+	}
+
+	udp := ip[headerLength:]
+	udpLength := int(binary.BigEndian.Uint16(udp[4:]))
+	if udpLength < 8 || udpLength > len(udp) {
+		return nil, nil, nil, false
+	}
+
+	from := &net.UDPAddr{IP: net.IP(append([]byte(nil), ip[12:16]...)), Port: int(binary.BigEndian.Uint16(udp[0:]))}
+	to := &net.UDPAddr{IP: net.IP(append([]byte(nil), ip[16:20]...)), Port: int(binary.BigEndian.Uint16(udp[2:]))}
+	return from, to, udp[8:udpLength], true
+}
+
+// ipFromFrame strips the link layer header off a captured frame, if it's carrying IPv4
+func ipFromFrame(linkType uint32, frame []byte) ([]byte, bool) {
+	switch linkType {
+	case linkRaw, linkIPv4:
+		return frame, true
+	case linkNull:
+		if len(frame) < 4 || (binary.LittleEndian.Uint32(frame) != 2 && binary.BigEndian.Uint32(frame) != 2) { // AF_INET, in whichever order the capturing machine used
+			return nil, false
+		}
+
+		return frame[4:], true
+	case linkEthernet:
+		offset := 12
+		for len(frame) >= offset+2 && (binary.BigEndian.Uint16(frame[offset:]) == 0x8100 || binary.BigEndian.Uint16(frame[offset:]) == 0x88a8) { // Skip VLAN tags
+			offset += 4
+		}
+
+		if len(frame) < offset+2 || binary.BigEndian.Uint16(frame[offset:]) != 0x0800 {
+			return nil, false
+		}
+
+		return frame[offset+2:], true
+	case linkLinuxSLL:
+		if len(frame) < 16 || binary.BigEndian.Uint16(frame[14:]) != 0x0800 {
+			return nil, false
+		}
+
+		return frame[16:], true
+	case linkLinuxSL2:
+		if len(frame) < 20 || binary.BigEndian.Uint16(frame[0:]) != 0x0800 {
+			return nil, false
+		}
+
+		return frame[20:], true
+	}
+
+	return nil, false
+}
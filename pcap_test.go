@@ -0,0 +1,145 @@
+package orvibo
+
+// testdata/discovery.pcap is an Ethernet capture with four frames: a discovery reply from an S20 at 192.168.1.50,
+// some mDNS, an ARP request, and a discovery reply from an AllOne at 192.168.1.51 with a VLAN tag
+
+import (
+	"bytes"           // For reading captures from memory
+	"encoding/binary" // For building frames
+	"net"             // For addresses
+	"os"              // For reading the fixture
+	"testing"
+)
+
+func TestReadPCAP(t *testing.T) {
+	f, err := os.Open("testdata/discovery.pcap")
+	if err != nil {
+		t.Fatalf("Couldn't open the fixture: %v", err)
+	}
+	defer f.Close()
+
+	var from, to []string
+	err = readPCAP(f, func(src *net.UDPAddr, dst *net.UDPAddr, payload []byte) {
+		from = append(from, src.String())
+		to = append(to, dst.String())
+	})
+	if err != nil {
+		t.Fatalf("readPCAP: %v", err)
+	}
+
+	// The ARP request isn't UDP, so it's skipped. The mDNS is, so it isn't
+	want := []string{"192.168.1.50:10000", "192.168.1.10:5353", "192.168.1.51:10000"}
+	if len(from) != len(want) {
+		t.Fatalf("Read packets from %v, not %v", from, want)
+	}
+
+	for i := range want {
+		if from[i] != want[i] {
+			t.Errorf("Packet %d is from %s, not %s", i, from[i], want[i])
+		}
+	}
+
+	if to[1] != "224.0.0.251:5353" {
+		t.Errorf("mDNS went to %s", to[1])
+	}
+}
+
+func TestReadPCAPBadHeader(t *testing.T) {
+	for name, header := range map[string][]byte{
+		"pcapng":    append([]byte{0x0a, 0x0d, 0x0d, 0x0a}, make([]byte, 20)...),
+		"not pcap":  make([]byte, 24),
+		"too short": {0xd4, 0xc3, 0xb2, 0xa1},
+	} {
+		if err := readPCAP(bytes.NewReader(header), func(*net.UDPAddr, *net.UDPAddr, []byte) {}); err == nil {
+			t.Errorf("Reading a %s capture should have failed", name)
+		}
+	}
+}
+
+func TestUDPFromFrame(t *testing.T) {
+	ip := testIPv4UDP([]byte{0x68, 0x64, 0x00, 0x06, 0x71, 0x61})
+	sll := append(make([]byte, 16), ip...)
+	binary.BigEndian.PutUint16(sll[14:], 0x0800)
+	sl2 := append(make([]byte, 20), ip...)
+	binary.BigEndian.PutUint16(sl2[0:], 0x0800)
+
+	for name, frame := range map[string]struct {
+		linkType uint32
+		frame    []byte
+	}{
+		"raw":             {linkRaw, ip},
+		"BSD loopback":    {linkNull, append([]byte{2, 0, 0, 0}, ip...)},
+		"Linux cooked":    {linkLinuxSLL, sll},
+		"Linux cooked v2": {linkLinuxSL2, sl2},
+		"padded raw":      {linkIPv4, append(append([]byte(nil), ip...), 0, 0, 0, 0)}, // Ethernet style padding
+	} {
+		from, to, payload, ok := udpFromFrame(frame.linkType, frame.frame)
+		if ok == false {
+			t.Errorf("Couldn't read a %s frame", name)
+			continue
+		}
+
+		if from.String() != "192.168.1.50:10000" || to.String() != "192.168.1.10:10000" || bytes.Equal(payload, []byte{0x68, 0x64, 0x00, 0x06, 0x71, 0x61}) == false {
+			t.Errorf("Got %s to %s with %x from a %s frame", from, to, payload, name)
+		}
+	}
+
+	fragment := append([]byte(nil), ip...)
+	fragment[6] = 0x20 // More fragments
+	if _, _, _, ok := udpFromFrame(linkRaw, fragment); ok {
+		t.Error("Fragments should be skipped")
+	}
+
+	tcp := append([]byte(nil), ip...)
+	tcp[9] = 6
+	if _, _, _, ok := udpFromFrame(linkRaw, tcp); ok {
+		t.Error("TCP should be skipped")
+	}
+
+	if _, _, _, ok := udpFromFrame(linkRaw, ip[:24]); ok {
+		t.Error("A cut off UDP header should be skipped")
+	}
+}
+
+func TestReplayPCAP(t *testing.T) {
+	c := NewClient()
+	replayed, err := c.ReplayPCAP("testdata/discovery.pcap")
+	if err != nil {
+		t.Fatalf("ReplayPCAP: %v", err)
+	}
+
+	if replayed != 2 { // The mDNS isn't Orvibo traffic
+		t.Errorf("Replayed %d packets, not 2", replayed)
+	}
+
+	found := map[string]bool{}
+	for len(c.Events) > 0 {
+		event := <-c.Events
+		if event.Name == "socketfound" || event.Name == "allonefound" {
+			found[event.DeviceInfo.MACAddress] = true
+		}
+	}
+
+	if found["accf00000001"] == false || found["accf00000002"] == false {
+		t.Errorf("Found %v, not both the socket and the AllOne", found)
+	}
+
+	if socket, ok := c.GetDevice("accf00000001"); ok == false || socket.State == false || socket.IP.String() != "192.168.1.50:10000" {
+		t.Errorf("Got %+v, not a socket at 192.168.1.50 that's switched on", socket)
+	}
+}
+
+// testIPv4UDP builds an IPv4 UDP packet from 192.168.1.50:10000 to 192.168.1.10:10000 carrying payload. We don't
+// check checksums, so it doesn't fill them in
+func testIPv4UDP(payload []byte) []byte {
+	ip := make([]byte, 28, 28+len(payload))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(28+len(payload)))
+	ip[8], ip[9] = 64, 17
+	copy(ip[12:], []byte{192, 168, 1, 50})
+	copy(ip[16:], []byte{192, 168, 1, 10})
+	binary.BigEndian.PutUint16(ip[20:], 10000)
+	binary.BigEndian.PutUint16(ip[22:], 10000)
+	binary.BigEndian.PutUint16(ip[24:], uint16(8+len(payload)))
+	return append(ip, payload...)
+}
@@ -0,0 +1,160 @@
+package orvibo
+
+// Saving and loading the devices we know about. A controller that restarts would otherwise have to wait
+// for discovery to find everything again. With LoadDevices, it can subscribe to them straight away, at
+// the addresses they had last time
+
+import (
+	"encoding/hex"  // For checking MAC addresses
+	"encoding/json" // For reading and writing the file
+	"fmt"           // For building our error messages
+	"io/ioutil"     // For reading and writing the file
+	"os"            // For replacing the file
+	"strings"       // For listing everything that's wrong with the file
+	"time"          // For LastSeen
+)
+
+// savedDevice is what we keep of a Device in the file. Everything else is found out again once it answers.
+// LastSeen isn't kept: a loaded device hasn't been seen since, so LoadDevices starts it from now anyway
+type savedDevice struct {
+	MACAddress string              `json:"mac"`
+	IP         string              `json:"ip"`
+	DeviceType int                 `json:"type"`
+	Model      string              `json:"model,omitempty"`
+	Name       string              `json:"name,omitempty"`
+	RFSwitches map[string]RFSwitch `json:"rfSwitches,omitempty"`
+	Curtains   map[string]Curtain  `json:"curtains,omitempty"`
+}
+
+// SaveDevices writes the devices we know about to a JSON file, for LoadDevices to read back in later.
+// Devices we don't have an IP address for are skipped, since we couldn't talk to them anyway
+func (c *Client) SaveDevices(path string) error {
+	var saved []savedDevice
+	devices := c.GetDevices() // Copies, so the listener can carry on while we write
+	for _, macAdd := range sortedMACs(devices) {
+		device := devices[macAdd]
+		if device.IP == nil {
+			continue
+		}
+
+		saved = append(saved, savedDevice{
+			MACAddress: device.MACAddress,
+			IP:         device.IP.IP.String(),
+			DeviceType: device.DeviceType,
+			Model:      device.Model,
+			Name:       device.Name,
+			RFSwitches: device.RFSwitches,
+			Curtains:   device.Curtains,
+		})
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(path, data)
+}
+
+// LoadDevices reads devices saved by SaveDevices, adds any we don't already know about to Devices, and subscribes
+// to them at their saved addresses. Each one raises the usual found event. Devices that have moved since won't
+// answer, so follow up with Discover to find them. Every device in the file is checked first, so if any of them
+// can't be loaded, none of them are. Call it after Prepare
+func (c *Client) LoadDevices(path string) error {
+	if err := c.checkReady(); err != nil { // We subscribe straight away, so we need our connection
+		return err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var saved []savedDevice
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("Couldn't read devices from %s: %v", path, err)
+	}
+
+	var devices []*Device
+	var problems []string
+	for _, s := range saved {
+		device, err := s.device()
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		devices = append(devices, device)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Couldn't load devices from %s: %s", path, strings.Join(problems, "; "))
+	}
+
+	for _, device := range devices {
+		c.devicesLock.Lock()
+		if _, found := c.Devices[device.MACAddress]; found { // Already found it (or it's in the file twice), so what we've got is newer
+			c.devicesLock.Unlock()
+			continue
+		}
+
+		c.deviceCount++
+		device.ID = c.deviceCount
+		c.Devices[device.MACAddress] = device
+		c.devicesLock.Unlock()
+
+		c.addDevice(device)
+		if c.AutoSubscribe == false { // addDevice has already subscribed otherwise
+			c.subscribeDevice(device)
+		}
+	}
+
+	return nil
+}
+
+// device turns a saved device back into a Device, checking it's something we could have saved
+func (s savedDevice) device() (*Device, error) {
+	if _, err := hex.DecodeString(s.MACAddress); err != nil || len(s.MACAddress) != 12 {
+		return nil, fmt.Errorf("%q isn't a MAC address", s.MACAddress)
+	}
+
+	if s.DeviceType != SOCKET && s.DeviceType != ALLONE {
+		return nil, fmt.Errorf("%s is a device of type %d. Only SOCKET and ALLONE are supported", s.MACAddress, s.DeviceType)
+	}
+
+	addr, err := deviceAddr(s.IP)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", s.MACAddress, err)
+	}
+
+	device := &Device{
+		DeviceType: s.DeviceType,
+		Model:      s.Model,
+		Name:       s.Name,
+		IP:         addr,
+		MACAddress: s.MACAddress,
+		RFSwitches: s.RFSwitches,
+		Curtains:   s.Curtains,
+		LastSeen:   time.Now(), // Like RegisterDevice, this stops PurgeStaleDevices throwing it away before it's had a chance to answer
+	}
+
+	if device.RFSwitches == nil {
+		device.RFSwitches = make(map[string]RFSwitch)
+	}
+
+	if device.Curtains == nil {
+		device.Curtains = make(map[string]Curtain)
+	}
+
+	return device, nil
+}
+
+// replaceFile writes data to path. We write to a temporary file first and then move it into place,
+// so a crash halfway through can't leave us with half a file
+func replaceFile(path string, data []byte) error {
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,74 @@
+package orvibo_test
+
+import (
+	"io/ioutil"     // For writing a bad file
+	"path/filepath" // For somewhere to put our files
+	"testing"
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/packet"
+)
+
+func TestSaveLoadDevices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+
+	c, _ := newTestClient(t)
+	registerSocket(t, c)
+	if _, err := c.RegisterDevice(testAllOne, "192.168.1.51", orvibo.ALLONE); err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+
+	if err := c.SaveDevices(path); err != nil {
+		t.Fatalf("SaveDevices: %v", err)
+	}
+
+	loaded, transport := newTestClient(t) // As if we'd restarted
+	if err := loaded.LoadDevices(path); err != nil {
+		t.Fatalf("LoadDevices: %v", err)
+	}
+
+	devices := loaded.GetDevices()
+	if len(devices) != 2 {
+		t.Fatalf("Loaded %d devices, not 2", len(devices))
+	}
+
+	if socket := devices[testSocket]; socket == nil || socket.DeviceType != orvibo.SOCKET || socket.IP.String() != testAddr.String() {
+		t.Errorf("Got %+v, not a socket at %s", socket, testAddr)
+	}
+
+	if allOne := devices[testAllOne]; allOne == nil || allOne.DeviceType != orvibo.ALLONE || allOne.IP.String() != "192.168.1.51:10000" {
+		t.Errorf("Got %+v, not an AllOne at 192.168.1.51", allOne)
+	}
+
+	if sent := sentWith(transport, packet.Subscribe); len(sent) != 2 { // Both are subscribed to straight away
+		t.Errorf("Sent %d subscriptions, not 2", len(sent))
+	}
+}
+
+func TestLoadDevicesBadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+
+	for name, data := range map[string]string{
+		"bad IP":      `[{"mac": "accf00000001", "ip": "192.168.1.50", "type": 0}, {"mac": "accf00000002", "ip": "nowhere", "type": 1}]`,
+		"bad MAC":     `[{"mac": "accf00000001", "ip": "192.168.1.50", "type": 0}, {"mac": "zz", "ip": "192.168.1.51", "type": 1}]`,
+		"bad type":    `[{"mac": "accf00000001", "ip": "192.168.1.50", "type": 0}, {"mac": "accf00000002", "ip": "192.168.1.51", "type": 7}]`,
+		"not devices": `{"mac": "accf00000001"}`,
+	} {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Couldn't write the file: %v", err)
+		}
+
+		c, transport := newTestClient(t)
+		if err := c.LoadDevices(path); err == nil {
+			t.Errorf("Loading a file with a %s in it should have failed", name)
+		}
+
+		if devices := c.GetDevices(); len(devices) != 0 { // Not even the good one
+			t.Errorf("Loaded %d devices from a file with a %s in it", len(devices), name)
+		}
+
+		if sent := transport.Sent(); len(sent) != 0 {
+			t.Errorf("Sent %d packets after loading a file with a %s in it", len(sent), name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package orvibo
+
+// Stale device purging. Long-running programs can end up holding on to devices that left the house
+// years ago, and we keep trying to subscribe to them. If DeviceTTL is set, they get cleaned up. If you
+// know a device has gone, RemoveDevice gets rid of it straight away
+
+import (
+	"fmt"  // For building our error messages
+	"time" // For working out how long it's been
+)
+
+// DeviceTTL is how long we can go without hearing from a device before it's purged. 0 (the default) means never.
+// Purging happens every time Discover() is called, or whenever you call PurgeStaleDevices() yourself
+var DeviceTTL time.Duration
+
+// ArchivePurged, if true, moves purged devices into ArchivedDevices instead of forgetting them entirely
+var ArchivePurged = false
+
+// ArchivedDevices holds devices that have been purged, if ArchivePurged is true. Keyed by MAC address, same as Devices
+var ArchivedDevices = make(map[string]*Device)
+
+// PurgeStaleDevices removes any devices we haven't heard from in DeviceTTL, raising "devicepurged" and then
+// "deviceremoved" for each one. Returns the MAC addresses of the devices that were purged
+func (c *Client) PurgeStaleDevices() []string {
+	var purged []string
+
+	if c.DeviceTTL <= 0 { // Purging is turned off
+		return purged
+	}
+
+	var snapshots []*Device
+	c.devicesLock.Lock()
+	for macAdd, device := range c.Devices {
+		if time.Since(device.LastSeen) < c.DeviceTTL {
+			continue
+		}
+
+		delete(c.Devices, macAdd)
+		if c.ArchivePurged {
+			c.ArchivedDevices[macAdd] = device
+		}
+
+		purged = append(purged, macAdd)
+		snapshots = append(snapshots, device.copy())
+	}
+	c.devicesLock.Unlock()
+
+	for _, snapshot := range snapshots { // Now we've let go of the lock, let the calling code know
+		c.passMessage("devicepurged", snapshot)
+		c.passMessage("deviceremoved", snapshot)
+	}
+
+	return purged
+}
+
+// RemoveDevice forgets about the device with the MAC address macAdd, for when it's been decommissioned and
+// you don't want us subscribing to it any more. Raises "deviceremoved". If it's still on the network, the next
+// Discover will find it again
+func (c *Client) RemoveDevice(macAdd string) error {
+	c.devicesLock.Lock()
+	device, found := c.Devices[macAdd]
+	if found == false {
+		c.devicesLock.Unlock()
+		return fmt.Errorf("%s isn't a device we know about", macAdd)
+	}
+
+	delete(c.Devices, macAdd)
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("deviceremoved", snapshot)
+	return nil
+}
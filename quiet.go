@@ -0,0 +1,61 @@
+package orvibo
+
+// Quiet windows are periods of the day when we refuse to send non-critical commands, either to
+// one device or to all of them. Handy if you don't want automations clicking relays in the nursery overnight
+
+import (
+	"fmt"  // For building our error messages
+	"time" // For working out what time of day it is
+)
+
+// QuietWindow is a period of the day during which non-critical commands are refused.
+// Start and End are times of day, so 20 * time.Hour is 8pm. If End is before Start, the window runs past midnight
+type QuietWindow struct {
+	MACAddress string        // Which device this window is for. Leave it blank to apply it to all devices
+	Start      time.Duration // When the window starts, as time since midnight
+	End        time.Duration // When the window ends, as time since midnight
+}
+
+// QuietWindows is a list of all the quiet windows we know about. Add to it directly, e.g.
+// orvibo.QuietWindows = append(orvibo.QuietWindows, orvibo.QuietWindow{MACAddress: "accf23aabbcc", Start: 20 * time.Hour, End: 7 * time.Hour})
+var QuietWindows []QuietWindow
+
+// Critical marks a command as critical, so it's sent even if the device is in a quiet window
+func Critical() CommandOption {
+	return func(o *commandOptions) {
+		o.critical = true
+	}
+}
+
+// contains checks if a particular time falls inside our window
+func (q QuietWindow) contains(t time.Time) bool {
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if q.Start <= q.End { // A window like 1pm to 3pm
+		return sinceMidnight >= q.Start && sinceMidnight < q.End
+	}
+
+	// A window like 8pm to 7am, which wraps around midnight
+	return sinceMidnight >= q.Start || sinceMidnight < q.End
+}
+
+// checkQuietWindows returns an error if the device is currently in a quiet window
+func checkQuietWindows(device *Device) error {
+	now := time.Now()
+	for _, q := range QuietWindows {
+		if q.MACAddress != "" && q.MACAddress != device.MACAddress { // Not for this device
+			continue
+		}
+
+		if q.contains(now) {
+			return fmt.Errorf("%s is in a quiet window (%s to %s). Pass Critical() to send anyway", device.MACAddress, clock(q.Start), clock(q.End))
+		}
+	}
+
+	return nil
+}
+
+// clock turns a time since midnight into something like 20:00
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours())%24, int(d.Minutes())%60)
+}
@@ -0,0 +1,62 @@
+package orvibo_test
+
+import (
+	"testing"
+	"time" // For our window
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/packet"
+)
+
+// withQuietWindows sets QuietWindows for one test, putting them back afterwards
+func withQuietWindows(t *testing.T, windows ...orvibo.QuietWindow) {
+	old := orvibo.QuietWindows
+	orvibo.QuietWindows = windows
+	t.Cleanup(func() { orvibo.QuietWindows = old })
+}
+
+func TestQuietWindow(t *testing.T) {
+	withQuietWindows(t, orvibo.QuietWindow{MACAddress: testSocket, Start: 0, End: 24 * time.Hour}) // All day
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+
+	if success, err := c.SetState(testSocket, true); success || err == nil {
+		t.Fatalf("SetState returned %v, %v in a quiet window", success, err)
+	}
+
+	expectEvent(t, c, "quietwindow")
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 0 {
+		t.Fatalf("Sent %v in a quiet window", sent)
+	}
+
+	if _, err := c.SetState(testSocket, true, orvibo.Critical()); err != nil { // Critical commands go anyway
+		t.Fatalf("SetState with Critical() returned %v", err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 1 {
+		t.Fatalf("Sent %d critical commands, not 1", len(sent))
+	}
+
+	orvibo.QuietWindows = nil // And once the window's over, so does everything else
+	if _, err := c.SetState(testSocket, false); err != nil {
+		t.Fatalf("SetState returned %v after the window", err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 2 {
+		t.Errorf("Sent %d commands, not 2", len(sent))
+	}
+}
+
+func TestQuietWindowOtherDevice(t *testing.T) {
+	withQuietWindows(t, orvibo.QuietWindow{MACAddress: "accf00000099", Start: 0, End: 24 * time.Hour})
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+
+	if _, err := c.SetState(testSocket, true); err != nil {
+		t.Fatalf("SetState returned %v for a device without a window", err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 1 {
+		t.Errorf("Sent %d commands, not 1", len(sent))
+	}
+}
@@ -0,0 +1,56 @@
+package orvibo
+
+// Device clocks and reboot detection. Each discovery reply includes the device's clock, which we keep on
+// the Device along with how far it's drifted from ours. When a device loses power it forgets that we've
+// subscribed to it, so we stop getting state changes and our commands get ignored. If its clock suddenly
+// goes backwards, that's what's happened, and we need to subscribe again
+
+import (
+	"time" // For clock maths
+
+	"github.com/Grayda/go-orvibo/packet" // For reading the clock out of discovery replies
+)
+
+// rebootTolerance is how far backwards a device's clock can jump before we decide it's rebooted.
+// Clocks drift a bit and get corrected by the device, so we don't want to be too twitchy
+const rebootTolerance = time.Minute
+
+// checkForReboot compares the clock in a discovery reply to the last one we saw. If the device's clock is
+// behind where it should be by now, it's restarted, so we treat it as a new device and subscribe again
+func (c *Client) checkForReboot(device *Device, message string) {
+	clock, ok := packet.Clock(message)
+	if ok == false {
+		return
+	}
+
+	c.devicesLock.Lock()
+	now := time.Now()
+	rebooted := false
+	if device.clockSeen.IsZero() == false { // We've seen this device's clock before, so we know where it should be
+		expected := device.DeviceTime.Add(now.Sub(device.clockSeen))
+		rebooted = clock.Before(expected.Add(-rebootTolerance))
+	}
+
+	device.DeviceTime = clock
+	device.ClockDrift = clock.Sub(now)
+	device.clockSeen = now
+	c.devicesLock.Unlock()
+
+	if rebooted {
+		c.handleReboot(device)
+	}
+}
+
+// handleReboot forgets our subscription to a device, lets the calling code know it restarted, then subscribes again.
+// Once the subscription is confirmed, handleMessage queries it and reconciles its state
+func (c *Client) handleReboot(device *Device) {
+	c.devicesLock.Lock()
+	device.Subscribed = false
+	device.Queried = false
+	device.rebooted = true
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("devicerebooted", snapshot)
+	c.subscribeDevice(device)
+}
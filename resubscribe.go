@@ -0,0 +1,75 @@
+package orvibo
+
+// Automatic resubscription. A subscription only lasts a few minutes, after which the device stops sending us
+// state changes and starts ignoring our commands. Rather than every program calling Subscribe on a timer, turn
+// on AutoResubscribe and we'll renew each subscription ourselves before it runs out
+
+import (
+	"time" // For working out when subscriptions are due
+)
+
+// AutoResubscribe, if true, renews the subscription to each device we've subscribed to before it lapses.
+// "subscriptionrenewed" is raised each time a device confirms, and "subscriptionlost" if one stops answering
+var AutoResubscribe = false
+
+// ResubscribeInterval is how long after a device last confirmed our subscription that we renew it.
+// Subscriptions last about 5 minutes, so this leaves plenty of room for a renewal to go missing
+var ResubscribeInterval = 3 * time.Minute
+
+// SubscriptionLifetime is how long a subscription lasts without being renewed. If a device hasn't confirmed
+// one in this long, it's stopped listening to us, so we raise "subscriptionlost" and keep trying
+var SubscriptionLifetime = 5 * time.Minute
+
+// resubscribeCheckInterval is how often we look for subscriptions that are due
+const resubscribeCheckInterval = 15 * time.Second
+
+// manageSubscriptions checks for subscriptions that are due every resubscribeCheckInterval, until Close is called.
+// It's started by Prepare, and does nothing unless AutoResubscribe is on
+func (c *Client) manageSubscriptions() {
+	ticker := time.NewTicker(resubscribeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+			if c.AutoResubscribe {
+				c.renewSubscriptions()
+			}
+		}
+	}
+}
+
+// renewSubscriptions subscribes again to every device whose subscription is due, and raises "subscriptionlost"
+// for any that have let theirs lapse. Devices we've never subscribed to are left alone
+func (c *Client) renewSubscriptions() {
+	for _, device := range c.deviceList() {
+		c.devicesLock.Lock()
+		if device.LastSubscribed.IsZero() { // Never subscribed, so there's nothing to renew
+			c.devicesLock.Unlock()
+			continue
+		}
+
+		since := time.Since(device.LastSubscribed)
+		if since < ResubscribeInterval {
+			c.devicesLock.Unlock()
+			continue
+		}
+
+		lost := since > SubscriptionLifetime && device.Subscribed
+		if lost {
+			device.Subscribed = false
+		}
+
+		device.renewing = true
+		snapshot := device.copy()
+		c.devicesLock.Unlock()
+
+		if lost {
+			c.passMessage("subscriptionlost", snapshot)
+		}
+
+		c.subscribeDevice(device)
+	}
+}
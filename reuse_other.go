@@ -0,0 +1,14 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package orvibo
+
+import (
+	"syscall" // For syscall.RawConn
+)
+
+// reuseControl would set SO_REUSEADDR and SO_REUSEPORT on our socket, but we only know how to on
+// Unix-like systems. Everywhere else, the port is ours alone
+func reuseControl(network string, address string, raw syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build aix || darwin || dragonfly || freebsd || netbsd || openbsd || (linux && !386 && !amd64 && !arm)
+// +build aix darwin dragonfly freebsd netbsd openbsd linux,!386,!amd64,!arm
+
+package orvibo
+
+import (
+	"syscall" // For SO_REUSEPORT
+)
+
+const soReusePort = syscall.SO_REUSEPORT
@@ -0,0 +1,8 @@
+//go:build linux && (386 || amd64 || arm)
+// +build linux
+// +build 386 amd64 arm
+
+package orvibo
+
+// soReusePort is SO_REUSEPORT, which the syscall package leaves out on these architectures
+const soReusePort = 0xf
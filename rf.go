@@ -0,0 +1,56 @@
+package orvibo
+
+// High level RF switch control. Once a switch has been paired with PairRFSwitch, we know its code, so it can
+// be turned on and off by name rather than by code.
+//
+// This depends on PairRFSwitch, which is experimental (see ExperimentalRF in learn.go). Until ExperimentalRF is
+// set, no switch gets a code, so SetRFState has nothing to send
+
+import (
+	"fmt" // For building our error messages
+)
+
+// RFStateChangedEvent is the Payload of an "rfstatechanged" event
+type RFStateChangedEvent struct {
+	Device   *Device // The AllOne that sent the command
+	SwitchID string
+	OldState bool
+	NewState bool
+}
+
+// SetRFState turns the RF switch switchID (as paired with PairRFSwitch) on or off, via the AllOne with the MAC
+// address allOneMAC. Once the command's gone out, RFSwitches[switchID].State is updated and "rfstatechanged" is raised.
+// The code it sends is the one PairRFSwitch learned, so this is only as reliable as ExperimentalRF's guess
+func (c *Client) SetRFState(allOneMAC string, switchID string, state bool, opts ...CommandOption) error {
+	device, err := c.knownDevice(allOneMAC)
+	if err != nil {
+		return err
+	}
+
+	c.devicesLock.RLock()
+	rfSwitch, found := device.RFSwitches[switchID]
+	c.devicesLock.RUnlock()
+
+	if found == false || rfSwitch.Code == "" {
+		return fmt.Errorf("%s hasn't been paired with an RF switch called %q. Use PairRFSwitch first", allOneMAC, switchID)
+	}
+
+	o := getCommandOptions(opts)
+	if err := c.emitRF(state, rfSwitch.Code, allOneMAC, o); err != nil {
+		return err
+	}
+
+	if o.dryRun { // Nothing was sent, so nothing's changed
+		return nil
+	}
+
+	c.devicesLock.Lock()
+	oldState := rfSwitch.State
+	rfSwitch.State = state
+	device.RFSwitches[switchID] = rfSwitch
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passEvent("rfstatechanged", snapshot, RFStateChangedEvent{Device: snapshot, SwitchID: switchID, OldState: oldState, NewState: state})
+	return nil
+}
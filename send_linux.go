@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package orvibo
+
+import (
+	"net"     // For networking stuff
+	"syscall" // For building our IP_PKTINFO control message
+	"unsafe"  // For filling in the control message
+)
+
+// writeToDevice sends buf to addr. If ifIndex is set, we attach an IP_PKTINFO control message so the packet
+// goes out that interface, rather than whichever one the routing table picks
+func (c *Client) writeToDevice(buf []byte, addr *net.UDPAddr, ifIndex int) (int, error) {
+	conn, isUDP := c.conn.(*net.UDPConn)
+	if ifIndex <= 0 || isUDP == false { // No interface to pick, or a Transport that can't pick one
+		return c.conn.WriteToUDP(buf, addr)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(syscall.SizeofInet4Pktinfo))
+	header := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	header.Level = syscall.IPPROTO_IP
+	header.Type = syscall.IP_PKTINFO
+	header.SetLen(syscall.CmsgLen(syscall.SizeofInet4Pktinfo))
+
+	info := (*syscall.Inet4Pktinfo)(unsafe.Pointer(&oob[syscall.CmsgLen(0)]))
+	info.Ifindex = int32(ifIndex)
+
+	n, _, err := conn.WriteMsgUDP(buf, oob, addr)
+	return n, err
+}
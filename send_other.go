@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package orvibo
+
+import (
+	"net" // For networking stuff
+)
+
+// writeToDevice sends buf to addr. Choosing the outgoing interface is only supported on Linux,
+// so everywhere else we leave it up to the routing table
+func (c *Client) writeToDevice(buf []byte, addr *net.UDPAddr, ifIndex int) (int, error) {
+	return c.conn.WriteToUDP(buf, addr)
+}
@@ -0,0 +1,66 @@
+package orvibo
+
+// We keep track of where we're at (not prepared yet, ready, or closed) so calling things in the wrong
+// order gives you an error, rather than a nil pointer panic from deep inside the net package
+
+import (
+	"errors"      // For crafting our own errors
+	"sync/atomic" // Close can be called from another goroutine while we're reading messages
+)
+
+const (
+	stateUnprepared int32 = iota // Prepare hasn't been called yet
+	stateReady                   // Prepare has been called and we have a connection
+	stateClosed                  // Close has been called. We're done
+)
+
+// ErrNotPrepared is returned when something needs our connection, but Prepare() hasn't been called yet
+var ErrNotPrepared = errors.New("Not prepared. Call Prepare() first")
+
+// ErrClosed is returned when something needs our connection, but Close() has already been called
+var ErrClosed = errors.New("Connection closed. Close() has already been called")
+
+// Close shuts everything down. It closes our UDP connection (freeing up our port), waits for the
+// background listener to finish, gives up on anything waiting for a device to answer, raises "closed",
+// and then closes Events (and any DiscoverStream or DeviceEvents channels), so a range over any of them
+// finishes once the last events have been read. Anything
+// that needs the connection after this returns ErrClosed. Don't call it from an On callback, as it
+// waits for the listener, which is what's running the callback
+func (c *Client) Close() error {
+	if atomic.SwapInt32(&c.state, stateClosed) != stateReady { // Never prepared, or already closed. Nothing to shut down
+		return nil
+	}
+
+	close(c.closing)      // Wake up anything waiting on a device
+	err := c.conn.Close() // Knocks the listener out of its read, too
+	c.Stop()              // And wait for it to finish
+	c.stopCoalescing()    // Don't pass on anything still in its coalescing window
+
+	c.passMessage("closed", &Device{})
+	c.closeEvents()
+	c.closeStreams()
+	return err
+}
+
+// closeEvents closes our Events channel. Anything raised after this is dropped, rather than panicking
+func (c *Client) closeEvents() {
+	c.eventsLock.Lock()
+	defer c.eventsLock.Unlock()
+
+	if c.eventsClosed == false {
+		c.eventsClosed = true
+		close(c.Events)
+	}
+}
+
+// checkReady returns ErrNotPrepared or ErrClosed if we can't use our connection yet (or any more)
+func (c *Client) checkReady() error {
+	switch atomic.LoadInt32(&c.state) {
+	case stateUnprepared:
+		return ErrNotPrepared
+	case stateClosed:
+		return ErrClosed
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package orvibo
+
+import (
+	"time" // For working out who's online
+)
+
+// OfflineAfter is how long we can go without hearing from a device before Stats counts it as offline, and the
+// health monitor raises "deviceoffline"
+var OfflineAfter = 10 * time.Minute
+
+// FleetStats is a summary of all the devices we know about, handy for status pages and the like
+type FleetStats struct {
+	Devices        int           // How many devices we know about
+	ByType         map[int]int   // How many of each DeviceType (SOCKET, ALLONE etc.) we know about
+	Subscribed     int           // How many devices we've subscribed to
+	Unsubscribed   int           // How many devices we haven't subscribed to (yet)
+	Queried        int           // How many devices we've queried
+	Online         int           // How many devices we've heard from in the last OfflineAfter
+	Offline        int           // How many devices we haven't heard from in the last OfflineAfter
+	Commands       int           // How many control commands are in the audit log
+	Acknowledged   int           // How many commands we waited on (Acknowledged(), SetStateSync etc.) have been acknowledged
+	AverageLatency time.Duration // How long, on average, devices took to acknowledge them. 0 if none have been
+}
+
+// Stats counts up all the devices we know about, and works out how quickly they've been answering our commands.
+// Only commands we wait on an answer for (those sent with Acknowledged(), SetStateSync and the like) are timed
+func (c *Client) Stats() FleetStats {
+	stats := FleetStats{ByType: make(map[int]int)}
+
+	c.devicesLock.RLock()
+	for _, device := range c.Devices {
+		stats.Devices++
+		stats.ByType[device.DeviceType]++
+
+		if device.Subscribed {
+			stats.Subscribed++
+		} else {
+			stats.Unsubscribed++
+		}
+
+		if device.Queried {
+			stats.Queried++
+		}
+
+		if time.Since(device.LastSeen) < OfflineAfter {
+			stats.Online++
+		} else {
+			stats.Offline++
+		}
+	}
+	c.devicesLock.RUnlock()
+
+	for _, entry := range AuditLog() {
+		if entry.Success && entry.DryRun == false { // Only count commands that actually went out
+			stats.Commands++
+		}
+	}
+
+	c.acksLock.Lock()
+	stats.Acknowledged = c.ackedCommands
+	if c.ackedCommands > 0 {
+		stats.AverageLatency = c.ackLatency / time.Duration(c.ackedCommands)
+	}
+	c.acksLock.Unlock()
+
+	return stats
+}
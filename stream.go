@@ -0,0 +1,124 @@
+package orvibo
+
+// Discovery streams let setup wizards and the like show devices as they're found, without having to
+// pick them out of the main Events channel. Device event streams do the same for the events of one device
+
+import (
+	"context" // For knowing when the caller is done with a stream
+)
+
+// DiscoverStreamSize is how many found devices a stream will hold if the caller isn't reading fast enough.
+// Anything past that is dropped, the same as Events
+var DiscoverStreamSize = 16
+
+// DiscoverStream broadcasts a discovery message, then returns a channel that gets a copy of every newly found device
+// until ctx is cancelled or Close is called, at which point the channel is closed. Messages still arrive via
+// CheckForMessages, so keep calling that as normal. Devices we already knew about aren't sent down the stream
+func (c *Client) DiscoverStream(ctx context.Context) <-chan *Device {
+	stream := make(chan *Device, DiscoverStreamSize)
+
+	c.discoverStreamsLock.Lock()
+	c.discoverStreams[stream] = true
+	c.discoverStreamsLock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.closing: // Close closes the stream itself
+		}
+
+		c.discoverStreamsLock.Lock()
+		if c.discoverStreams[stream] { // Close might have got to it first
+			delete(c.discoverStreams, stream)
+			close(stream)
+		}
+		c.discoverStreamsLock.Unlock()
+	}()
+
+	c.Discover()
+	return stream
+}
+
+// streamDevice sends a newly found device to every open discovery stream. It doesn't block, so a
+// stream nobody is reading from can't hold up our message handling
+func (c *Client) streamDevice(device *Device) {
+	c.discoverStreamsLock.Lock()
+	defer c.discoverStreamsLock.Unlock()
+
+	for stream := range c.discoverStreams {
+		select {
+		case stream <- device:
+		default:
+		}
+	}
+}
+
+// DeviceEvents returns a channel that gets every event raised for the device with the MAC address macAdd,
+// until ctx is cancelled or Close is called, at which point the channel is closed. The events still go out on Events too.
+// Like Events, it holds EventBufferSize events, and anything past that is dropped
+func (c *Client) DeviceEvents(ctx context.Context, macAdd string) <-chan EventStruct {
+	stream := make(chan EventStruct, EventBufferSize)
+
+	c.deviceStreamsLock.Lock()
+	if c.deviceStreams[macAdd] == nil {
+		c.deviceStreams[macAdd] = make(map[chan EventStruct]bool)
+	}
+	c.deviceStreams[macAdd][stream] = true
+	c.deviceStreamsLock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.closing:
+		}
+
+		c.deviceStreamsLock.Lock()
+		if c.deviceStreams[macAdd][stream] { // Close might have got to it first
+			delete(c.deviceStreams[macAdd], stream)
+			if len(c.deviceStreams[macAdd]) == 0 {
+				delete(c.deviceStreams, macAdd)
+			}
+			close(stream)
+		}
+		c.deviceStreamsLock.Unlock()
+	}()
+
+	return stream
+}
+
+// streamEvent sends an event to every open stream for its device. Like streamDevice, it doesn't block
+func (c *Client) streamEvent(event EventStruct) {
+	if event.DeviceInfo == nil || event.DeviceInfo.MACAddress == "" { // Not about any one device
+		return
+	}
+
+	c.deviceStreamsLock.Lock()
+	defer c.deviceStreamsLock.Unlock()
+
+	for stream := range c.deviceStreams[event.DeviceInfo.MACAddress] {
+		select {
+		case stream <- event:
+		default:
+		}
+	}
+}
+
+// closeStreams closes every discovery and device event stream. Used when we're closing down, so anything ranging
+// over one finishes, whatever its ctx is
+func (c *Client) closeStreams() {
+	c.discoverStreamsLock.Lock()
+	for stream := range c.discoverStreams {
+		delete(c.discoverStreams, stream)
+		close(stream)
+	}
+	c.discoverStreamsLock.Unlock()
+
+	c.deviceStreamsLock.Lock()
+	for macAdd, streams := range c.deviceStreams {
+		for stream := range streams {
+			close(stream)
+		}
+		delete(c.deviceStreams, macAdd)
+	}
+	c.deviceStreamsLock.Unlock()
+}
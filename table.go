@@ -0,0 +1,210 @@
+package orvibo
+
+// Table 4 holds a device's details: its name, remote password, versions, network settings, timezone and so on.
+// We keep the last copy of the record we read, so changing one field can write the rest back exactly as they were.
+//
+// The record starts 28 bytes into a read table response. Offsets below are bytes into the record:
+//
+//	0   Record length (2)      2   Record ID (2)       4   Version (2)
+//	6   MAC address and padding, then the reversed MAC address and padding (24)
+//	30  Remote password (12)   42  Name (16)           58  Icon (2)
+//	60  Hardware version (4)   64  Firmware version (4)   68  Wi-Fi firmware version (4)
+//	72  Server settings and domain name (48)
+//	120 Local IP, gateway and netmask (4 each)
+//	132 DHCP (1)   133 Discoverable (1)   134 Timezone set (1)   135 Timezone (1)
+//	136 Countdown status (2)   138 Countdown (2)
+
+import (
+	"encoding/binary" // For reading version numbers
+	"encoding/hex"    // For converting to and from hex
+	"errors"          // For crafting our own errors
+	"fmt"             // For building packets and error messages
+	"strings"         // For padding
+
+	"github.com/Grayda/go-orvibo/packet" // For command IDs and reading packets
+)
+
+// tableFourStart is where the table 4 record starts in a read table response, in hex characters (28 bytes in)
+const tableFourStart = 56
+
+// Where each field is in the record, in bytes
+const (
+	passwordField     = 30
+	passwordLength    = 12
+	nameField         = 42
+	hardwareField     = 60
+	firmwareField     = 64
+	wifiFirmwareField = 68
+	dhcpField         = 132
+	discoverableField = 133
+	timezoneSetField  = 134
+	timezoneField     = 135
+)
+
+// storeTableFour keeps a copy of the record from a table 4 response, and reads its fields onto the device.
+// Hold devicesLock while calling it
+func storeTableFour(device *Device, message string) {
+	if len(message) <= tableFourStart {
+		return
+	}
+
+	device.tableFour = message[tableFourStart:]
+
+	record, err := hex.DecodeString(device.tableFour)
+	if err != nil || len(record) < timezoneField+1 { // Too short to have all our fields in it
+		return
+	}
+
+	device.RemotePassword = strings.TrimRight(string(record[passwordField:passwordField+passwordLength]), " \x00\xff")
+	device.HardwareVersion = binary.LittleEndian.Uint32(record[hardwareField:])
+	device.FirmwareVersion = binary.LittleEndian.Uint32(record[firmwareField:])
+	device.WifiFirmwareVersion = binary.LittleEndian.Uint32(record[wifiFirmwareField:])
+	device.DHCP = record[dhcpField] != 0
+	device.Discoverable = record[discoverableField] != 0
+	device.TimezoneSet = record[timezoneSetField] != 0
+	device.Timezone = int(int8(record[timezoneField]))
+}
+
+// setField returns a copy of a record (as hex) with the bytes at offset replaced by value (also hex)
+func setField(record string, offset int, value string) (string, error) {
+	start := offset * 2
+	if len(record) < start+len(value) {
+		return "", errors.New("Table 4 record is too short to have that field in it")
+	}
+
+	return record[:start] + value + record[start+len(value):], nil
+}
+
+// SetRemotePassword changes the password the WiWo app uses to control a device from outside your network.
+// It can be up to 12 bytes long. Waits for the device to accept it
+func (c *Client) SetRemotePassword(macAdd string, password string) error {
+	if len(password) > passwordLength {
+		return fmt.Errorf("Remote password is %d bytes long, but devices only have room for %d", len(password), passwordLength)
+	}
+
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	padded := hex.EncodeToString([]byte(password + strings.Repeat(" ", passwordLength-len(password))))
+	err = c.modifyTableFour("SetRemotePassword", device, func(record string) (string, error) {
+		return setField(record, passwordField, padded)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.devicesLock.Lock()
+	device.RemotePassword = password
+	c.devicesLock.Unlock()
+	return nil
+}
+
+// SetTimezone sets a device's timezone, as hours from UTC (e.g. 10 for Brisbane). Waits for the device to accept it
+func (c *Client) SetTimezone(macAdd string, hours int) error {
+	if hours < -12 || hours > 14 {
+		return fmt.Errorf("%d isn't a valid timezone. It should be between -12 and 14 hours", hours)
+	}
+
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	err = c.modifyTableFour("SetTimezone", device, func(record string) (string, error) {
+		return setField(record, timezoneSetField, "01"+hex.EncodeToString([]byte{byte(int8(hours))}))
+	})
+	if err != nil {
+		return err
+	}
+
+	c.devicesLock.Lock()
+	device.Timezone = hours
+	device.TimezoneSet = true
+	c.devicesLock.Unlock()
+	return nil
+}
+
+// SetDiscoverable sets whether a device answers discovery broadcasts. A device that isn't discoverable
+// can still be found if you know its MAC address. Waits for the device to accept it
+func (c *Client) SetDiscoverable(macAdd string, discoverable bool) error {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	value := "00"
+	if discoverable {
+		value = "01"
+	}
+
+	err = c.modifyTableFour("SetDiscoverable", device, func(record string) (string, error) {
+		return setField(record, discoverableField, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.devicesLock.Lock()
+	device.Discoverable = discoverable
+	c.devicesLock.Unlock()
+	return nil
+}
+
+// readTableFour queries a device for table 4 and waits for the answer. It reads messages itself unless Listen has been called
+func (c *Client) readTableFour(device *Device) error {
+	msg, err := packet.NewPacket(packet.ReadTable, device.MACAddress, tableQuery(4))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.sendCommand("Query", msg, device, commandOptions{ack: true, critical: true}, packet.ReadTable, func(message string) bool {
+		table, _ := packet.Table(message)
+		return table == 4
+	})
+
+	return err
+}
+
+// tableQuery is the payload of a read table (rt) command for the given table
+func tableQuery(table int) string {
+	return fmt.Sprintf("00000000%02x000000000000", table)
+}
+
+// modifyTableFour reads table 4 (if we haven't already), lets change edit the record, writes it back with the
+// table modify (tm) command, and waits for the device to accept it. change gets the record as hex, and returns the new one
+func (c *Client) modifyTableFour(action string, device *Device, change func(record string) (string, error)) error {
+	c.devicesLock.RLock()
+	tableFour := device.tableFour
+	c.devicesLock.RUnlock()
+
+	if tableFour == "" { // We need the rest of the record before we can change part of it
+		if err := c.readTableFour(device); err != nil {
+			return err
+		}
+
+		c.devicesLock.RLock()
+		tableFour = device.tableFour
+		c.devicesLock.RUnlock()
+	}
+
+	record, err := change(tableFour)
+	if err != nil {
+		return err
+	}
+
+	msg, err := packet.NewPacket(packet.ModifyTable, device.MACAddress, "00000000"+"0400"+tableUpdate+record)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.sendCommand(action, msg, device, commandOptions{ack: true, critical: true}, packet.ModifyTable, nil); err != nil {
+		return err
+	}
+
+	c.devicesLock.Lock()
+	device.tableFour = record
+	c.devicesLock.Unlock()
+	return nil
+}
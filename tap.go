@@ -0,0 +1,67 @@
+package orvibo
+
+// Raw packet taps, for tooling that wants to see every datagram as it goes in and out (reverse engineering new
+// hardware, say) without running tcpdump alongside. Trace does the same thing for a file, with the fields decoded
+
+import (
+	"net" // For knowing who a packet was to or from
+)
+
+// Direction is which way a packet was going
+type Direction int
+
+// The ways a packet can go
+const (
+	Inbound  Direction = iota // A packet we received
+	Outbound                  // A packet we sent
+)
+
+// String returns "in" or "out", the same as a TraceFrame's Direction
+func (d Direction) String() string {
+	if d == Outbound {
+		return "out"
+	}
+
+	return "in"
+}
+
+// RawPacketHandler is a function that's called with every packet we send or receive
+type RawPacketHandler func(direction Direction, data []byte, addr *net.UDPAddr)
+
+// OnRawPacket calls handler with every packet we receive (before we try to read it) and every packet we send
+// (once it's gone out). data is handler's own copy, so it can keep it. Like On, handlers are called on whichever
+// goroutine sent or received the packet, so keep them quick
+func (c *Client) OnRawPacket(handler RawPacketHandler) {
+	c.rawTapsLock.Lock()
+	defer c.rawTapsLock.Unlock()
+
+	c.rawTaps = append(c.rawTaps, handler)
+}
+
+// OffRawPacket removes every handler registered with OnRawPacket
+func (c *Client) OffRawPacket() {
+	c.rawTapsLock.Lock()
+	defer c.rawTapsLock.Unlock()
+
+	c.rawTaps = nil
+}
+
+// tapPacket hands a packet to every handler registered with OnRawPacket. A handler that panics is logged
+// and skipped, rather than taking down the listener
+func (c *Client) tapPacket(direction Direction, data []byte, addr *net.UDPAddr) {
+	c.rawTapsLock.Lock()
+	taps := c.rawTaps
+	c.rawTapsLock.Unlock()
+
+	for _, tap := range taps {
+		func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					getLogger().Error("Raw packet handler panicked: %v", recovered)
+				}
+			}()
+
+			tap(direction, append([]byte(nil), data...), addr)
+		}()
+	}
+}
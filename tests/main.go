@@ -3,26 +3,24 @@ package main
 import (
 	"fmt" // For outputting messages
 
-	"time" // For setInterval()
+	"time" // For our discovery interval
 
 	"github.com/Grayda/go-orvibo"     // For controlling Orvibo stuff
 	"github.com/davecgh/go-spew/spew" // For neatly outputting stuff
 )
 
 func main() {
-	// These are our SetIntervals that run. To cancel one, simply send "<- true" to it (e.g. autoDiscover <- true)
-	var autoDiscover, resubscribe chan bool
+	orvibo.AutoSubscribe = true   // Subscribe to new devices as soon as they're found
+	orvibo.AutoQuery = true       // And query them as soon as we've subscribed
+	orvibo.AutoResubscribe = true // And keep our subscriptions from running out
 
 	ready, err := orvibo.Prepare() // You ready?
 	if ready == true {             // Yep! Let's do this!
-		// Look for new devices every minute
-		autoDiscover = setInterval(orvibo.Discover, time.Minute)
-		// Resubscription should happen every 5 minutes, but we make it 3, just to be on the safe side
-		resubscribe = setInterval(orvibo.Subscribe, time.Minute*3)
-		orvibo.Discover() // Discover all sockets
+		orvibo.Listen()                        // Read incoming messages in the background. They'll turn up on orvibo.Events
+		orvibo.StartAutoDiscovery(time.Minute) // Discover all sockets now, then look for new ones every minute
 
 		for { // Loop forever
-			select { // This lets us do non-blocking channel reads. If we have a message, process it. If not, check for UDP data and loop
+			select { // Wait for an event, then process it
 			case msg := <-orvibo.Events:
 				switch msg.Name {
 				case "ready": // We're set up and ready to go
@@ -42,45 +40,41 @@ func main() {
 				case "buttonpress": // Someone's pressed the button on top of our AllOne
 					fmt.Println("Button on", msg.DeviceInfo.Name, "has been pressed")
 				case "ircode": // We've learned an IR code, and this is what the code is
-					fmt.Println("IR code found!", msg.DeviceInfo.LastIRMessage)
+					learned := msg.Payload.(orvibo.IRLearnedEvent)
+					fmt.Println("IR code found!", learned.Code)
 				case "existingsocketfound": // We've found a socket that we already know about. Can be used for resubscription purposes?
 					fallthrough
 				case "existingallonefound":
 					fallthrough
 				case "socketfound": // We've found a socket!
 					fmt.Println("Socket found! MAC address is", msg.DeviceInfo.MACAddress)
-					orvibo.Subscribe() // Subscribe to any unsubscribed sockets
-					orvibo.Query()     // And query any unqueried sockets
 				case "allonefound": // We've found an AllOne!
 					fmt.Println("AllOne found! MAC address is", msg.DeviceInfo.MACAddress)
-					orvibo.Subscribe() // Subscribe to any unsubscribed sockets
-					orvibo.Query()     // And query any unqueried sockets
-				case "subscribed": // We've subscribed to a device, and it's been successful
-					if msg.DeviceInfo.Subscribed == false {
-						fmt.Println("Subscription successful!")
-						orvibo.Devices[msg.DeviceInfo.MACAddress].Subscribed = true
-						orvibo.Query()
-					}
-					orvibo.Query()
+				case "subscribed": // We've subscribed to a device, and it's been successful. The library marks it as Subscribed for us
+					fmt.Println("Subscription successful!")
+				case "deviceready": // We've subscribed to and queried a device, so we know its name and state
+					fmt.Println(msg.DeviceInfo.Name, "is ready. State is", msg.DeviceInfo.State)
 				case "queried": // We've successfully queried a device and can now access its reported name and so forth
-					if msg.DeviceInfo.Queried == false {
-						orvibo.Devices[msg.DeviceInfo.MACAddress].Queried = true
-						// spew.Dump(msg.DeviceInfo)
+					// spew.Dump(msg.DeviceInfo)
+					if msg.DeviceInfo.DeviceType == orvibo.ALLONE { // Turn on the RF switch with the code 2b00daaeeb
+						if err := orvibo.EmitRF(true, "2b00daaeeb", msg.DeviceInfo.MACAddress); err != nil {
+							fmt.Println("Couldn't switch RF switch:", err)
+						}
 					}
 
-					orvibo.EmitRF(true, "2b00daaeeb", msg.DeviceInfo.MACAddress)
-
 				case "rfswitch": // Someone's toggled an RF switch. Still in alpha stage
 					fmt.Println("RF switch pressed")
 					spew.Dump(msg.DeviceInfo.RFSwitches)
+				case "devicerebooted": // A device has restarted. The library subscribes to it again for us
+					fmt.Println(msg.DeviceInfo.Name, "has rebooted. Resubscribing")
+				case "subscriptionlost": // A device has stopped confirming our subscription. The library keeps trying
+					fmt.Println("Lost our subscription to", msg.DeviceInfo.Name)
 				case "statechanged": // Something external has triggered a state change, or we've got confirmation of a state change
-					fmt.Println("State of", msg.DeviceInfo.Name, "changed to:", msg.DeviceInfo.State)
+					changed := msg.Payload.(orvibo.StateChangedEvent)
+					fmt.Println("State of", msg.DeviceInfo.Name, "changed from", changed.OldState, "to", changed.NewState)
 				case "quit": // Not used.
-					autoDiscover <- true
-					resubscribe <- true
+					orvibo.StopAutoDiscovery()
 				}
-			default: // No messages? Check for new messages
-				orvibo.CheckForMessages()
 			}
 
 		}
@@ -90,20 +84,3 @@ func main() {
 	}
 
 }
-
-func setInterval(what func(), delay time.Duration) chan bool {
-	stop := make(chan bool)
-
-	go func() {
-		for {
-			what()
-			select {
-			case <-time.After(delay):
-			case <-stop:
-				return
-			}
-		}
-	}()
-
-	return stop
-}
@@ -0,0 +1,233 @@
+package orvibo
+
+// Socket timers (schedules). Sockets keep their timers in table 3, one record per timer, which we read
+// with the read table (rt) command and change with the table modify (tm) command. The record layout comes
+// from captures of the WiWo app, and hasn't been tested against every firmware, so if your timers look
+// wrong, please pass on your LastMessage to the developer!
+//
+// Each record is laid out like this (all numbers little-endian):
+//
+//	Record length  2 bytes (always 28, not counting these two bytes)
+//	Record ID      2 bytes
+//	Name           16 bytes, padded with spaces
+//	State          2 bytes (0 for off, 1 for on)
+//	Year           2 bytes
+//	Month, day, hour, minute, second   1 byte each
+//	Repeat         1 byte. The top bit turns repeating on, the other bits are the days, Monday (bit 0) to Sunday (bit 6)
+
+import (
+	"encoding/binary" // For reading and writing our little-endian numbers
+	"encoding/hex"    // For converting to and from hex
+	"errors"          // For crafting our own errors
+	"fmt"             // For building packets and error messages
+	"strings"         // For padding
+	"time"            // For timer times
+
+	"github.com/Grayda/go-orvibo/packet" // For command IDs and reading packets
+)
+
+// Timer is a single scheduled on or off
+type Timer struct {
+	ID    int            // The record ID on the socket. Leave it as 0 when adding a new timer
+	Name  string         // A name for the timer. Optional, up to 16 bytes
+	State bool           // Whether the socket turns on (true) or off (false)
+	Time  time.Time      // When it happens. If Days is set, only the time of day matters
+	Days  []time.Weekday // Which days of the week it repeats on. Leave it empty for a one-off timer
+}
+
+// timerRecordLength is how long a timer record is, not counting the length field itself
+const timerRecordLength = 28
+
+// Table modify actions. The byte after the table number says what we're doing to the record
+const (
+	tableAdd    = "00" // Add a new record
+	tableUpdate = "01" // Change an existing record
+	tableDelete = "02" // Delete a record
+)
+
+// dayBit maps a weekday to its bit in the repeat byte. Monday is bit 0
+func dayBit(day time.Weekday) byte {
+	return 1 << uint((int(day)+6)%7)
+}
+
+// encode turns our Timer into a table 3 record, as hex
+func (t Timer) encode() (string, error) {
+	encodedName := strings.Repeat("20", MaxNameLength) // Timers don't need a name, so this is all spaces if there isn't one
+	if t.Name != "" {
+		var err error
+		if encodedName, err = packet.EncodeName(t.Name); err != nil {
+			return "", err
+		}
+	}
+
+	record := make([]byte, 12)
+	binary.LittleEndian.PutUint16(record[0:2], timerRecordLength)
+	binary.LittleEndian.PutUint16(record[2:4], uint16(t.ID))
+
+	if t.State {
+		binary.LittleEndian.PutUint16(record[4:6], 1)
+	}
+
+	binary.LittleEndian.PutUint16(record[6:8], uint16(t.Time.Year()))
+	record[8] = byte(t.Time.Month())
+	record[9] = byte(t.Time.Day())
+
+	clock := []byte{byte(t.Time.Hour()), byte(t.Time.Minute()), byte(t.Time.Second()), 0}
+	for _, day := range t.Days {
+		clock[3] |= 0x80 | dayBit(day)
+	}
+
+	// Length and ID, then the name, then the rest
+	return hex.EncodeToString(record[0:4]) + encodedName + hex.EncodeToString(record[4:10]) + hex.EncodeToString(clock), nil
+}
+
+// parseTimers reads all the timer records out of a table 3 response. Records start 28 bytes in
+func parseTimers(message string) ([]Timer, error) {
+	b, err := hex.DecodeString(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var timers []Timer
+	for offset := 28; offset+2 <= len(b); {
+		length := int(binary.LittleEndian.Uint16(b[offset : offset+2]))
+		record := b[offset+2:]
+		if length < timerRecordLength || len(record) < length {
+			return timers, fmt.Errorf("Timer record at byte %d is %d bytes, but we expected at least %d", offset, length, timerRecordLength)
+		}
+
+		t := Timer{
+			ID:    int(binary.LittleEndian.Uint16(record[0:2])),
+			Name:  packet.DecodeName(record[2:18]),
+			State: binary.LittleEndian.Uint16(record[18:20]) != 0,
+			Time: time.Date(int(binary.LittleEndian.Uint16(record[20:22])), time.Month(record[22]), int(record[23]),
+				int(record[24]), int(record[25]), int(record[26]), 0, time.Local),
+		}
+
+		if repeat := record[27]; repeat&0x80 != 0 {
+			for day := time.Sunday; day <= time.Saturday; day++ {
+				if repeat&dayBit(day) != 0 {
+					t.Days = append(t.Days, day)
+				}
+			}
+		}
+
+		timers = append(timers, t)
+		offset += 2 + length
+	}
+
+	return timers, nil
+}
+
+// handleTimerTable deals with a table 3 (timers) response, storing the timers on the device
+func (c *Client) handleTimerTable(message string, device *Device) (bool, error) {
+	timers, err := parseTimers(message)
+	if err != nil {
+		return false, err
+	}
+
+	c.devicesLock.Lock()
+	device.Timers = timers
+	device.LastMessage = message // Set our LastMessage
+	snapshot := device.copy()
+	c.devicesLock.Unlock()
+
+	c.passMessage("timers", snapshot)
+	return true, nil
+}
+
+// GetTimers reads the timers off a socket. It waits for the answer, so it reads messages itself
+// unless Listen has been called. The timers are also stored in the device's Timers
+func (c *Client) GetTimers(macAdd string) ([]Timer, error) {
+	device, err := c.timerDevice(macAdd)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := packet.NewPacket(packet.ReadTable, macAdd, tableQuery(3))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.sendCommand("GetTimers", msg, device, commandOptions{ack: true, critical: true}, packet.ReadTable, func(message string) bool {
+		table, _ := packet.Table(message)
+		return table == 3
+	}); err != nil {
+		return nil, err
+	}
+
+	c.devicesLock.RLock()
+	defer c.devicesLock.RUnlock()
+	return append([]Timer(nil), device.Timers...), nil
+}
+
+// SetTimer adds a timer to a socket (if its ID is 0) or changes an existing one, then reads the timers back.
+// New timers get the next free ID
+func (c *Client) SetTimer(macAdd string, timer Timer) error {
+	timers, err := c.GetTimers(macAdd) // So we know what IDs are taken, and have the latest list afterwards
+	if err != nil {
+		return err
+	}
+
+	action := tableUpdate
+	if timer.ID == 0 {
+		action = tableAdd
+		timer.ID = 1
+		for _, t := range timers {
+			if t.ID >= timer.ID {
+				timer.ID = t.ID + 1
+			}
+		}
+	}
+
+	record, err := timer.encode()
+	if err != nil {
+		return err
+	}
+
+	return c.modifyTimers(macAdd, action+record)
+}
+
+// DeleteTimer removes a timer from a socket, then reads the timers back
+func (c *Client) DeleteTimer(macAdd string, id int) error {
+	record := make([]byte, 4)
+	binary.LittleEndian.PutUint16(record[0:2], 2) // A delete record is just the ID
+	binary.LittleEndian.PutUint16(record[2:4], uint16(id))
+
+	return c.modifyTimers(macAdd, tableDelete+hex.EncodeToString(record))
+}
+
+// modifyTimers sends a table modify (tm) command for table 3, waits for the socket to accept it,
+// then reads the timers back so the device's Timers are up to date
+func (c *Client) modifyTimers(macAdd string, payload string) error {
+	device, err := c.timerDevice(macAdd)
+	if err != nil {
+		return err
+	}
+
+	msg, err := packet.NewPacket(packet.ModifyTable, macAdd, "00000000"+"0300"+payload)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.sendCommand("SetTimer", msg, device, commandOptions{ack: true, critical: true}, packet.ModifyTable, nil); err != nil {
+		return err
+	}
+
+	_, err = c.GetTimers(macAdd)
+	return err
+}
+
+// timerDevice finds a socket we can read and write timers on
+func (c *Client) timerDevice(macAdd string) (*Device, error) {
+	device, err := c.knownDevice(macAdd)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.DeviceType != SOCKET {
+		return nil, errors.New("Only sockets have timers")
+	}
+
+	return device, nil
+}
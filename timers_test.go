@@ -0,0 +1,60 @@
+package orvibo
+
+import (
+	"reflect" // For comparing timers
+	"strings" // For the table header
+	"testing"
+	"time"
+)
+
+func TestTimerRoundTrip(t *testing.T) {
+	timers := []Timer{
+		{ID: 1, Name: "Morning", State: true, Time: time.Date(2026, time.October, 16, 7, 30, 0, 0, time.Local), Days: []time.Weekday{time.Sunday, time.Monday, time.Friday}},
+		{ID: 2, State: false, Time: time.Date(2026, time.December, 31, 23, 59, 59, 0, time.Local)}, // A one-off, with no name
+	}
+
+	message := strings.Repeat("00", 28) // Records start 28 bytes in
+	for _, timer := range timers {
+		record, err := timer.encode()
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+
+		if len(record) != (2+timerRecordLength)*2 {
+			t.Fatalf("Record %s is %d bytes, not %d", record, len(record)/2, 2+timerRecordLength)
+		}
+
+		message += record
+	}
+
+	parsed, err := parseTimers(message)
+	if err != nil {
+		t.Fatalf("parseTimers: %v", err)
+	}
+
+	if reflect.DeepEqual(parsed, timers) == false {
+		t.Errorf("Got %+v back, not %+v", parsed, timers)
+	}
+}
+
+func TestTimerRepeatByte(t *testing.T) {
+	record, _ := Timer{Days: []time.Weekday{time.Monday, time.Sunday}}.encode()
+	if repeat := record[len(record)-2:]; repeat != "c1" { // Repeating, Monday (bit 0) and Sunday (bit 6)
+		t.Errorf("Repeat byte is %s, not c1", repeat)
+	}
+
+	if _, err := (Timer{Name: strings.Repeat("a", MaxNameLength+1)}).encode(); err == nil {
+		t.Error("A name that's too long should have been rejected")
+	}
+}
+
+func TestParseTimersShortRecord(t *testing.T) {
+	record, _ := Timer{ID: 1, Time: time.Now()}.encode()
+	if _, err := parseTimers(strings.Repeat("00", 28) + record[:20]); err == nil {
+		t.Error("A cut off record should have been an error")
+	}
+
+	if timers, err := parseTimers(strings.Repeat("00", 28)); err != nil || len(timers) != 0 { // An empty table
+		t.Errorf("Got %v, %v from an empty table", timers, err)
+	}
+}
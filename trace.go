@@ -0,0 +1,99 @@
+package orvibo
+
+// Tracing writes every packet we send and receive as a line of JSON, with the fields we know how to read already
+// picked out. It's a lot easier to attach a trace to a bug report than to walk someone through Wireshark
+
+import (
+	"encoding/json" // For writing our trace lines
+	"io"            // For writing our trace somewhere
+	"net"           // For knowing who a packet was to or from
+	"sync"          // Packets can be traced from more than one goroutine
+	"time"          // For timestamps
+
+	"github.com/Grayda/go-orvibo/packet" // For reading packets
+)
+
+// Trace, if set, gets a line of JSON (a TraceFrame) for every packet we send or receive. Set it to os.Stdout, a file etc.
+var Trace io.Writer
+
+var traceLock sync.Mutex // Stops two trace lines being written over the top of each other
+
+// TraceFrame is one packet, as written to Trace
+type TraceFrame struct {
+	Direction string                 `json:"direction"`        // "in" for packets we received, "out" for packets we sent
+	Time      time.Time              `json:"time"`             // When we sent or received it
+	Peer      string                 `json:"peer"`             // Who it came from or went to (IP and port)
+	Command   string                 `json:"command"`          // The two letter command name (e.g. qa for discovery)
+	CommandID string                 `json:"commandID"`        // The command ID as hex (e.g. 7161)
+	Fields    map[string]interface{} `json:"fields,omitempty"` // Anything we could read out of the packet (MAC address, state, name etc.)
+	Raw       string                 `json:"raw"`              // The whole packet, as hex
+}
+
+// traceFrame writes a packet to Trace, if it's set
+func traceFrame(direction string, message string, addr *net.UDPAddr) {
+	if Trace == nil {
+		return
+	}
+
+	frame := TraceFrame{Direction: direction, Time: time.Now(), Fields: traceFields(message), Raw: message}
+	if addr != nil {
+		frame.Peer = addr.String()
+	}
+
+	if commandID, ok := packet.CommandID(message); ok {
+		frame.CommandID = commandID
+		frame.Command = packet.CommandName(commandID)
+	}
+
+	line, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+
+	traceLock.Lock()
+	defer traceLock.Unlock()
+	Trace.Write(append(line, '\n'))
+}
+
+// traceFields picks out everything we know how to read from a packet
+func traceFields(message string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if length, ok := packet.Length(message); ok {
+		fields["length"] = length
+	}
+
+	if len(message) >= 24 { // Everything but a discovery broadcast has a MAC address 6 bytes in
+		fields["mac"] = message[12:24]
+	}
+
+	commandID, _ := packet.CommandID(message)
+	switch commandID {
+	case packet.Discover, packet.DiscoverMAC:
+		if model, ok := packet.Model(message); ok {
+			fields["model"] = model
+		}
+
+		if clock, ok := packet.Clock(message); ok {
+			fields["clock"] = clock
+			fields["state"] = packet.State(message)
+		}
+	case packet.Subscribe, packet.StateChanged, packet.StateControl:
+		if len(message) > 24 {
+			fields["state"] = packet.State(message)
+		}
+	case packet.ReadTable:
+		if name, ok := packet.Name(message); ok {
+			fields["name"] = name
+		}
+
+		if countdown, ok := packet.Countdown(message); ok {
+			fields["countdown"] = countdown.String()
+		}
+	case packet.Learn:
+		if len(message) > 52 {
+			fields["code"] = message[52:]
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,26 @@
+package orvibo
+
+// Transports. Normally we talk to devices over a UDP connection of our own, but anything that can send and
+// receive datagrams will do. Tests can hand us a fake one with WithTransport, record what we send, and feed
+// in canned replies, without opening a real socket or needing a network at all
+
+import (
+	"net"  // For addresses
+	"time" // For read deadlines
+)
+
+// Transport is how we send and receive packets. *net.UDPConn is one
+type Transport interface {
+	// ReadFromUDP blocks until a packet arrives, the read deadline passes, or the Transport is closed
+	ReadFromUDP(b []byte) (int, *net.UDPAddr, error)
+
+	// WriteToUDP sends a packet to addr
+	WriteToUDP(b []byte, addr *net.UDPAddr) (int, error)
+
+	// SetReadDeadline makes ReadFromUDP give up at t. The zero time means wait forever. It has to wake up
+	// a ReadFromUDP that's already waiting, since that's how Stop knocks the listener out of its read
+	SetReadDeadline(t time.Time) error
+
+	// Close stops the Transport. Anything waiting in ReadFromUDP has to return
+	Close() error
+}
@@ -0,0 +1,377 @@
+package orvibo_test
+
+// Tests that run a Client against orvibotest's fake Transport, so nothing touches the network. The "device" is
+// whatever the test feeds in with Deliver, built the same way a real S20 or AllOne lays out its packets
+
+import (
+	"context"      // For streams
+	"encoding/hex" // For building discovery replies
+	"net"          // For addresses
+	"strings"      // For checking what we sent
+	"sync"         // For counting what we've answered
+	"testing"
+	"time" // For timeouts
+
+	"github.com/Grayda/go-orvibo"
+	"github.com/Grayda/go-orvibo/orvibotest"
+	"github.com/Grayda/go-orvibo/packet"
+)
+
+const (
+	testSocket = "accf00000001" // The MAC address of our pretend socket
+	testAllOne = "accf00000002" // The MAC address of our pretend AllOne
+)
+
+// testAddr is where our pretend devices live
+var testAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 1, 50), Port: 10000}
+
+// newTestClient prepares a Client on a fake Transport. It's closed when the test finishes
+func newTestClient(t *testing.T) (*orvibo.Client, *orvibotest.Transport) {
+	t.Helper()
+
+	transport := orvibotest.NewTransport()
+	c := orvibo.NewClient(orvibo.WithTransport(transport))
+	if _, err := c.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+
+	t.Cleanup(func() { c.Close() })
+	return c, transport
+}
+
+// withAckSettings sets AckTimeout and AckRetries for one test, putting them back afterwards
+func withAckSettings(t *testing.T, timeout time.Duration, retries int) {
+	oldTimeout, oldRetries := orvibo.AckTimeout, orvibo.AckRetries
+	orvibo.AckTimeout, orvibo.AckRetries = timeout, retries
+	t.Cleanup(func() { orvibo.AckTimeout, orvibo.AckRetries = oldTimeout, oldRetries })
+}
+
+// expectEvent reads events until one called name turns up, failing the test if it takes more than a second
+func expectEvent(t *testing.T, c *orvibo.Client, name string) orvibo.EventStruct {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-c.Events:
+			if event.Name == name {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("No %q event", name)
+			return orvibo.EventStruct{}
+		}
+	}
+}
+
+// sentWith returns every packet we've sent with the command ID commandID
+func sentWith(transport *orvibotest.Transport, commandID string) []orvibotest.SentPacket {
+	var sent []orvibotest.SentPacket
+	for _, p := range transport.Sent() {
+		if frame, err := packet.Parse(p.Data); err == nil && frame.CommandID == commandID {
+			sent = append(sent, p)
+		}
+	}
+
+	return sent
+}
+
+// answer plays the device: every time the Client sends a packet with the command ID commandID, reply is called
+// with it, and whatever it returns is delivered, in order. It stops when the test finishes
+func answer(t *testing.T, transport *orvibotest.Transport, commandID string, reply func(sent orvibotest.SentPacket) []string) {
+	stop := make(chan bool)
+	done := make(chan bool)
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+
+	go func() {
+		defer close(done)
+
+		seen := 0
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+
+			sent := transport.Sent()
+			for _, p := range sent[seen:] {
+				if frame, err := packet.Parse(p.Data); err == nil && frame.CommandID == commandID {
+					for _, msg := range reply(p) {
+						transport.Deliver(msg, testAddr)
+					}
+				}
+			}
+
+			seen = len(sent)
+		}
+	}()
+}
+
+// devicePacket builds a packet from one of our pretend devices, failing the test if it can't
+func devicePacket(t *testing.T, commandID string, macAdd string, payload string) string {
+	msg, err := packet.NewPacket(commandID, macAdd, payload)
+	if err != nil {
+		t.Errorf("Couldn't build a %s packet: %v", commandID, err)
+	}
+
+	return msg
+}
+
+// discoveryReply is what a device sends back when it's discovered. The state is in the last byte
+func discoveryReply(t *testing.T, macAdd string, model string, state string) string {
+	return devicePacket(t, packet.Discover, "", "00"+macAdd+packet.Padding+packet.ReverseMAC(macAdd)+packet.Padding+
+		hex.EncodeToString([]byte(model))+packet.EncodeClock(time.Now())+state)
+}
+
+// registerSocket adds our pretend socket to c, without going through discovery
+func registerSocket(t *testing.T, c *orvibo.Client) {
+	if _, err := c.RegisterDevice(testSocket, testAddr.IP.String(), orvibo.SOCKET); err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	c, transport := newTestClient(t)
+
+	c.Discover()
+	if len(sentWith(transport, packet.Discover)) == 0 {
+		t.Fatal("Discover didn't broadcast anything")
+	}
+
+	transport.Deliver(discoveryReply(t, testSocket, "SOC002", "01"), testAddr)
+	if _, err := c.CheckForMessages(); err != nil {
+		t.Fatalf("CheckForMessages: %v", err)
+	}
+
+	found := expectEvent(t, c, "socketfound")
+	if found.DeviceInfo.MACAddress != testSocket {
+		t.Errorf("socketfound is for %s, not %s", found.DeviceInfo.MACAddress, testSocket)
+	}
+
+	device, ok := c.GetDevice(testSocket)
+	if ok == false {
+		t.Fatal("The socket isn't in Devices")
+	}
+
+	if device.DeviceType != orvibo.SOCKET || device.Model != "SOC002" || device.State == false || device.IP.String() != testAddr.String() {
+		t.Errorf("Got %+v, not an S20 at %s that's switched on", device, testAddr)
+	}
+
+	transport.Deliver(discoveryReply(t, testSocket, "SOC002", "01"), testAddr) // Hearing from it again isn't a new device
+	c.CheckForMessages()
+	expectEvent(t, c, "existingsocketfound")
+}
+
+func TestSubscribe(t *testing.T) {
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+
+	if err := c.SubscribeDevice(testSocket); err != nil {
+		t.Fatalf("SubscribeDevice: %v", err)
+	}
+
+	sent := sentWith(transport, packet.Subscribe)
+	if len(sent) != 1 {
+		t.Fatalf("Sent %d subscriptions, not 1", len(sent))
+	}
+
+	if sent[0].To.String() != testAddr.String() || strings.Contains(sent[0].Data, testSocket+packet.Padding+packet.ReverseMAC(testSocket)) == false {
+		t.Errorf("Subscription %s to %s isn't for %s at %s", sent[0].Data, sent[0].To, testSocket, testAddr)
+	}
+
+	transport.Deliver(devicePacket(t, packet.Subscribe, testSocket, "0000000000"+"01"), testAddr)
+	c.CheckForMessages()
+
+	subscribed := expectEvent(t, c, "subscribed")
+	if subscribed.DeviceInfo.Subscribed == false || subscribed.DeviceInfo.State == false {
+		t.Errorf("Got %+v, not a subscribed socket that's switched on", subscribed.DeviceInfo)
+	}
+}
+
+func TestSetStateSync(t *testing.T) {
+	withAckSettings(t, 50*time.Millisecond, 3)
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	c.Listen()
+
+	answer(t, transport, packet.StateControl, func(sent orvibotest.SentPacket) []string { // Confirm whatever we're told
+		return []string{devicePacket(t, packet.StateChanged, testSocket, "00000000"+sent.Data[len(sent.Data)-2:])}
+	})
+
+	if err := c.SetStateSync(testSocket, true, time.Second); err != nil {
+		t.Fatalf("SetStateSync: %v", err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 1 || strings.HasSuffix(sent[0].Data, "01") == false {
+		t.Errorf("Sent %v, not one command to switch on", sent)
+	}
+
+	changed := expectEvent(t, c, "statechanged").Payload.(orvibo.StateChangedEvent)
+	if changed.NewState == false {
+		t.Error("statechanged says the socket is off")
+	}
+
+	if device, _ := c.GetDevice(testSocket); device.State == false {
+		t.Error("The socket isn't on")
+	}
+}
+
+func TestSetStateSyncTimesOut(t *testing.T) {
+	withAckSettings(t, 20*time.Millisecond, 3)
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	c.Listen()
+
+	if err := c.SetStateSync(testSocket, true, 200*time.Millisecond); err != orvibo.ErrNoAck {
+		t.Fatalf("SetStateSync returned %v, not ErrNoAck", err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) < 2 {
+		t.Errorf("Sent the command %d times. It should have been sent again", len(sent))
+	}
+}
+
+func TestAcknowledgedRetries(t *testing.T) {
+	withAckSettings(t, 20*time.Millisecond, 2)
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	c.Listen()
+
+	var lock sync.Mutex
+	heard := 0
+	answer(t, transport, packet.StateControl, func(sent orvibotest.SentPacket) []string { // Only answer the second time
+		lock.Lock()
+		defer lock.Unlock()
+
+		heard++
+		if heard < 2 {
+			return nil
+		}
+
+		return []string{devicePacket(t, packet.StateChanged, testSocket, "00000000"+"01")}
+	})
+
+	if success, err := c.SetState(testSocket, true, orvibo.Acknowledged()); success == false || err != nil {
+		t.Fatalf("SetState returned %v, %v", success, err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 2 {
+		t.Errorf("Sent the command %d times, not 2", len(sent))
+	}
+
+	if stats := c.Stats(); stats.Acknowledged != 1 {
+		t.Errorf("Stats counted %d acknowledged commands, not 1", stats.Acknowledged)
+	}
+}
+
+func TestAcknowledgedGivesUp(t *testing.T) {
+	withAckSettings(t, 10*time.Millisecond, 2)
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+	c.Listen()
+
+	if _, err := c.SetState(testSocket, true, orvibo.Acknowledged()); err != orvibo.ErrNoAck {
+		t.Fatalf("SetState returned %v, not ErrNoAck", err)
+	}
+
+	if sent := sentWith(transport, packet.StateControl); len(sent) != 3 { // Once, then AckRetries more times
+		t.Errorf("Sent the command %d times, not 3", len(sent))
+	}
+
+	expectEvent(t, c, "acktimeout")
+}
+
+func TestLearnIR(t *testing.T) {
+	c, transport := newTestClient(t)
+	if _, err := c.RegisterDevice(testAllOne, testAddr.IP.String(), orvibo.ALLONE); err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+
+	c.Listen()
+
+	const code = "aabbccddeeff"
+	answer(t, transport, packet.Learn, func(sent orvibotest.SentPacket) []string { // Say we're learning, then hear a code
+		return []string{
+			devicePacket(t, packet.Learn, testAllOne, "000000000000"),
+			devicePacket(t, packet.Learn, testAllOne, "000000000000"+"0600"+code), // The code's length is little endian
+		}
+	})
+
+	learned, err := c.LearnIR(testAllOne, time.Second)
+	if err != nil {
+		t.Fatalf("LearnIR: %v", err)
+	}
+
+	if learned != code {
+		t.Errorf("Learned %s, not %s", learned, code)
+	}
+
+	if event := expectEvent(t, c, "ircode").Payload.(orvibo.IRLearnedEvent); event.Code != code {
+		t.Errorf("ircode has %s, not %s", event.Code, code)
+	}
+}
+
+func TestEmitIRErrors(t *testing.T) {
+	if err := orvibo.NewClient().EmitIR("aabbcc", testAllOne); err != orvibo.ErrNotPrepared {
+		t.Errorf("EmitIR before Prepare returned %v, not ErrNotPrepared", err)
+	}
+
+	c, transport := newTestClient(t)
+	registerSocket(t, c)
+
+	if err := c.EmitIR("aabbcc", testAllOne); err == nil { // We haven't heard of it
+		t.Error("EmitIR to an unknown AllOne didn't return an error")
+	}
+
+	if err := c.EmitIR("aabbcc", testSocket); err == nil {
+		t.Error("EmitIR to a socket didn't return an error")
+	}
+
+	if len(sentWith(transport, packet.EmitIR)) != 0 {
+		t.Error("EmitIR sent something when it shouldn't have")
+	}
+
+	if _, err := c.RegisterDevice(testAllOne, testAddr.IP.String(), orvibo.ALLONE); err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+
+	if err := c.EmitIR("aabbcc", testAllOne); err != nil {
+		t.Errorf("EmitIR returned %v", err)
+	}
+
+	c.Close()
+	if err := c.EmitIR("aabbcc", testAllOne); err != orvibo.ErrClosed {
+		t.Errorf("EmitIR after Close returned %v, not ErrClosed", err)
+	}
+}
+
+func TestCloseEndsStreams(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	discovered := c.DiscoverStream(context.Background())
+	events := c.DeviceEvents(context.Background(), testSocket)
+	c.Close()
+
+	done := make(chan bool)
+	go func() {
+		for range discovered {
+		}
+		for range events {
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't close the streams")
+	}
+
+	if _, open := <-c.DiscoverStream(context.Background()); open { // Opened after Close, so it's closed straight away
+		t.Error("DiscoverStream after Close gave us a device")
+	}
+}
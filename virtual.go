@@ -0,0 +1,159 @@
+package orvibo
+
+// Virtual sockets. These work the other way around to the rest of the library: instead of controlling a real socket,
+// we pretend to be one. The WiWo app (or anything else that speaks the protocol) will find them, subscribe to them
+// and switch them on and off, and we hand that off to your code. So a script, or a relay on a GPIO pin, can show up
+// as an Orvibo socket in the official app
+
+import (
+	"encoding/hex" // For building our replies
+	"errors"       // For crafting our own errors
+	"fmt"          // For building our replies
+	"net"          // For knowing who to reply to
+	"strings"      // For string manipulation
+	"sync"         // For locking our list of virtual sockets
+	"time"         // For our fake clock
+
+	"github.com/Grayda/go-orvibo/packet" // For command IDs and building our replies
+)
+
+// VirtualSocket is a socket that only exists in software
+type VirtualSocket struct {
+	MACAddress string // A made up MAC address, as 12 hex characters (e.g. accf00000001). Must be unique on your network
+	Name       string // The name that shows up in the app. Up to 16 bytes
+	State      bool   // Is it on or off?
+
+	// OnStateChange is called when someone asks to turn the socket on or off. Return an error to refuse, and the
+	// socket stays as it is. Leave it nil to just track State
+	OnStateChange func(state bool) error
+}
+
+var virtualSockets = make(map[string]*VirtualSocket) // All our virtual sockets, keyed by MAC address
+var virtualSocketsLock sync.Mutex                    // Sockets can be added while we're handling messages
+
+// virtualModel is the model our virtual sockets claim to be. It's what an S20 reports
+const virtualModel = "SOC002"
+
+// AddVirtualSocket starts answering discovery, subscription, query and state packets as the given socket
+func AddVirtualSocket(socket *VirtualSocket) error {
+	socket.MACAddress = strings.ToLower(socket.MACAddress)
+	if mac, err := hex.DecodeString(socket.MACAddress); err != nil || len(mac) != 6 {
+		return fmt.Errorf("%q isn't a valid MAC address. It should be 12 hex characters", socket.MACAddress)
+	}
+
+	if err := ValidateName(socket.Name); err != nil {
+		return err
+	}
+
+	virtualSocketsLock.Lock()
+	defer virtualSocketsLock.Unlock()
+
+	if _, found := virtualSockets[socket.MACAddress]; found {
+		return errors.New("There's already a virtual socket with MAC address " + socket.MACAddress)
+	}
+
+	virtualSockets[socket.MACAddress] = socket
+	return nil
+}
+
+// RemoveVirtualSocket stops answering as the socket with the given MAC address
+func RemoveVirtualSocket(macAdd string) {
+	virtualSocketsLock.Lock()
+	defer virtualSocketsLock.Unlock()
+
+	delete(virtualSockets, strings.ToLower(macAdd))
+}
+
+// answerVirtualDiscovery replies to a discovery broadcast on behalf of all our virtual sockets
+func (c *Client) answerVirtualDiscovery(addr *net.UDPAddr) {
+	virtualSocketsLock.Lock()
+	defer virtualSocketsLock.Unlock()
+
+	for _, socket := range virtualSockets {
+		c.SendMessage(socket.discoveryReply(packet.Discover), &Device{IP: addr})
+	}
+}
+
+// handleVirtualMessage checks if a message is for one of our virtual sockets and if so, answers it.
+// Returns true if it was, so handleMessage knows not to treat it as a message from a real device.
+// Messages to a device always have its MAC address 6 bytes in
+func (c *Client) handleVirtualMessage(message string, addr *net.UDPAddr) bool {
+	if len(message) < 24 {
+		return false
+	}
+
+	virtualSocketsLock.Lock()
+	socket, found := virtualSockets[message[12:24]]
+	virtualSocketsLock.Unlock()
+	if found == false {
+		return false
+	}
+
+	reply := &Device{IP: addr}
+	switch message[8:12] {
+	case packet.DiscoverMAC: // Someone's looking for this socket specifically
+		c.SendMessage(socket.discoveryReply(packet.DiscoverMAC), reply)
+	case packet.Subscribe: // Someone wants to subscribe. We always say yes, and tell them our state
+		msg, _ := packet.NewPacket(packet.Subscribe, socket.MACAddress, "0000000000"+socket.stateBit()) // Our MAC address was checked in AddVirtualSocket
+		c.SendMessage(msg, reply)
+	case packet.StateControl: // Someone wants to turn us on or off
+		if len(message) < 46 {
+			return true
+		}
+
+		state := message[(len(message)-1):] != "0"
+		if socket.OnStateChange == nil || socket.OnStateChange(state) == nil { // Nobody said no, so switch
+			socket.State = state
+			c.passMessage("virtualstatechanged", socket.device())
+		}
+
+		// Either way, tell them what state we're actually in
+		msg, _ := packet.NewPacket(packet.StateChanged, socket.MACAddress, "00000000"+socket.stateBit())
+		c.SendMessage(msg, reply)
+	case packet.ReadTable: // Someone wants to read one of our tables. We only have table 4 (our details)
+		if len(message) >= 46 && message[44:46] == "04" {
+			c.SendMessage(socket.tableFour(), reply)
+		}
+	}
+
+	return true
+}
+
+// stateBit is our state as the last byte of a packet
+func (socket *VirtualSocket) stateBit() string {
+	if socket.State {
+		return "01"
+	}
+
+	return "00"
+}
+
+// device gives us a Device describing our virtual socket, for passing along with events
+func (socket *VirtualSocket) device() *Device {
+	return &Device{Name: socket.Name, DeviceType: SOCKET, Model: virtualModel, MACAddress: socket.MACAddress, State: socket.State}
+}
+
+// discoveryReply builds the same reply a real socket sends when it's discovered. commandID is 7161 or 7167,
+// depending on which kind of discovery we're replying to
+func (socket *VirtualSocket) discoveryReply(commandID string) string {
+	// Discovery replies have a status byte before the MAC address, so we put the MAC address in the payload ourselves
+	msg, _ := packet.NewPacket(commandID, "", "00"+socket.MACAddress+twenties+packet.ReverseMAC(socket.MACAddress)+twenties+
+		hex.EncodeToString([]byte(virtualModel))+packet.EncodeClock(time.Now())+socket.stateBit())
+	return msg
+}
+
+// tableFour builds a table 4 response (our details), laid out the same way a real S20 does it
+func (socket *VirtualSocket) tableFour() string {
+	name, _ := packet.EncodeName(socket.Name) // Already validated in AddVirtualSocket
+
+	msg, _ := packet.NewPacket(packet.ReadTable, socket.MACAddress, "0200000000"+"0400"+"0100"+"00"+"8a00"+ // Status, table number and record length
+		"0100"+"4325"+socket.MACAddress+twenties+packet.ReverseMAC(socket.MACAddress)+twenties+ // Record ID, version, MAC addresses
+		hex.EncodeToString([]byte("888888      "))+name+"0400"+ // Remote password, name and icon
+		"20000000"+"1a000000"+"05000000"+ // Hardware, firmware and wifi firmware versions
+		"1027"+"00000000"+"1027"+strings.Repeat("20", 40)+ // Server port, server IP and port, and domain name (none)
+		"00000000"+"00000000"+"00000000"+ // Local IP, gateway and netmask (we use DHCP, so these are blank)
+		"01"+"01"+"00"+"00"+ // DHCP, discoverable, timezone set, timezone
+		"0000"+"0000") // Countdown status and countdown (no countdown)
+
+	return msg
+}
@@ -0,0 +1,90 @@
+package orvibo
+
+// Helpers for setup scripts and the like, which want to block until a device is ready, rather than
+// running their own event loop
+
+import (
+	"context" // For cancelling and timing out
+	"time"    // For read deadlines and retry intervals
+)
+
+// waitPollInterval is how long we wait for a message before checking on things again
+const waitPollInterval = 250 * time.Millisecond
+
+// waitRetryInterval is how long we give a device to answer before asking it again
+const waitRetryInterval = 2 * time.Second
+
+// WaitForDevice blocks until the device with the given MAC address has been discovered, subscribed to and queried,
+// or ctx is done. It does the discovering, subscribing and querying itself, and reads messages while it waits
+// (unless Listen has been called), so don't call it while another goroutine is calling CheckForMessages. The Device
+// returned is a copy, like GetDevice's
+func (c *Client) WaitForDevice(ctx context.Context, macAdd string) (*Device, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	var lastAsked time.Time // When we last nudged the device along
+
+	for {
+		device, found := c.device(macAdd)
+		subscribed, queried := false, false
+		if found {
+			c.devicesLock.RLock()
+			subscribed, queried = device.Subscribed, device.Queried
+			c.devicesLock.RUnlock()
+		}
+
+		if subscribed && queried { // All done!
+			return c.snapshot(device), nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if time.Since(lastAsked) > waitRetryInterval { // Haven't heard back for a while (or haven't asked yet), so ask
+			if found == false {
+				c.Discover()
+			} else if subscribed == false {
+				c.subscribeDevice(device)
+			} else {
+				c.queryDevice(device)
+			}
+
+			lastAsked = time.Now()
+		}
+
+		if err := c.pumpMessages(ctx, waitPollInterval); err != nil { // Closed while we were waiting
+			return nil, err
+		}
+	}
+}
+
+// pumpMessages reads and handles one message, waiting no longer than wait (or until ctx is done, if that's sooner).
+// If the background listener is running, it's already reading for us, so we just wait. Returns ErrClosed (or
+// ErrNotPrepared) if there's no connection to read from, so whoever's waiting can give up
+func (c *Client) pumpMessages(ctx context.Context, wait time.Duration) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(wait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if c.listening() {
+		select {
+		case <-time.After(time.Until(deadline)):
+		case <-ctx.Done():
+		case <-c.closing: // Close was called while we were waiting
+		}
+
+		return c.checkReady()
+	}
+
+	c.conn.SetReadDeadline(deadline)
+	c.CheckForMessages()
+	c.conn.SetReadDeadline(time.Time{}) // Back to blocking reads for everyone else
+	return c.checkReady()
+}